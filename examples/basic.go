@@ -10,11 +10,14 @@ import (
 )
 
 func main() {
-	tp := nucleus.APIKeyTransport{
-		APIKey: os.Getenv("NUCLEUS_API_KEY"),
+	// Read the API key from NUCLEUS_API_KEY, falling back to the profile
+	// named after the organisation in ~/.config/nucleus/credentials.
+	org := os.Getenv("NUCLEUS_ORG")
+	client, err := nucleus.NewClientWithOptions(org,
+		nucleus.WithCredentials(nucleus.DefaultCredentials(org)))
+	if err != nil {
+		log.Fatalln(err)
 	}
-
-	client := nucleus.NewClient(os.Getenv("NUCLEUS_ORG"), tp.Client())
 	ctx := context.Background()
 
 	projects, _, err := client.Projects.ListProjects(ctx)
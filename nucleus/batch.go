@@ -0,0 +1,210 @@
+package nucleus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultBatchParallelism is the number of items RunBatch processes at once
+// when BatchOptions.Parallelism is not set.
+const DefaultBatchParallelism = 4
+
+// ErrBatchSkipped is the error of the items of a fail-fast batch that were
+// not started because another item failed.
+var ErrBatchSkipped = errors.New("nucleus: batch item skipped")
+
+// BatchOptions configures RunBatch.
+type BatchOptions struct {
+	// Parallelism is the maximum number of items processed at once,
+	// DefaultBatchParallelism if not positive.
+	Parallelism int
+
+	// Ordered returns the results in the order of the items rather than in
+	// the order they complete.
+	Ordered bool
+
+	// FailFast stops the batch at the first error: the context of the
+	// running items is cancelled and the remaining items are skipped.
+	// Otherwise every item is processed and the errors collected.
+	FailFast bool
+
+	// Progress, if set, is called after each item completes or is skipped
+	// with the number of items done so far, which reaches total by the last
+	// call. Calls are not concurrent.
+	Progress func(done, total int)
+}
+
+// BatchFunc processes the item at index i of a batch.
+type BatchFunc func(ctx context.Context, i int) (interface{}, error)
+
+// BatchResult is the outcome of processing one item of a batch.
+type BatchResult struct {
+	Index int
+	Value interface{}
+	Err   error
+}
+
+// BatchError reports the items of a batch that failed.
+type BatchError struct {
+	// Errors holds the error of each failed item, keyed by index.
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+	i := e.first()
+	return fmt.Sprintf("%d batch items failed, item %d: %v", len(e.Errors), i, e.Errors[i])
+}
+
+// Unwrap returns the error of the failed item with the lowest index.
+func (e *BatchError) Unwrap() error {
+	return e.Errors[e.first()]
+}
+
+func (e *BatchError) first() int {
+	idx := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	return idx[0]
+}
+
+// RunBatch calls fn for the n items of a batch, processing at most
+// opts.Parallelism at once, and returns a result for every item. Items not
+// started because ctx is done have ctx.Err() as error.
+//
+// In fail-fast mode the first error is returned. Otherwise, if any item
+// failed, a *BatchError is returned. In both cases ctx.Err() takes precedence
+// when ctx is done.
+func RunBatch(ctx context.Context, n int, opts BatchOptions, fn BatchFunc) ([]BatchResult, error) {
+	if ctx == nil {
+		return nil, errNilContext
+	}
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultBatchParallelism
+	}
+	if parallelism > n {
+		parallelism = n
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	out := make(chan BatchResult)
+	started := make([]bool, n)
+
+	go func() {
+		defer close(jobs)
+		for i := 0; i < n; i++ {
+			if runCtx.Err() != nil {
+				return
+			}
+			select {
+			case jobs <- i:
+				started[i] = true
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				v, err := fn(runCtx, i)
+				out <- BatchResult{Index: i, Value: v, Err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	results := make([]BatchResult, 0, n)
+	var (
+		firstErr error
+		errs     map[int]error
+	)
+	for r := range out {
+		results = append(results, r)
+		if opts.Progress != nil {
+			opts.Progress(len(results), n)
+		}
+		if r.Err != nil {
+			if errs == nil {
+				errs = map[int]error{}
+			}
+			errs[r.Index] = r.Err
+			if firstErr == nil {
+				firstErr = r.Err
+				if opts.FailFast {
+					cancel()
+				}
+			}
+		}
+	}
+
+	// The feeder has returned once out is closed, so started is final.
+	for i, ok := range started {
+		if !ok {
+			err := ctx.Err()
+			if err == nil {
+				err = ErrBatchSkipped
+			}
+			results = append(results, BatchResult{Index: i, Err: err})
+			if opts.Progress != nil {
+				opts.Progress(len(results), n)
+			}
+		}
+	}
+	if opts.Ordered {
+		sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return results, ctx.Err()
+	case firstErr == nil:
+		return results, nil
+	case opts.FailFast:
+		return results, firstErr
+	default:
+		return results, &BatchError{Errors: errs}
+	}
+}
+
+// ForEachAsset calls fn for each of assets, as RunBatch does for the items of
+// a batch.
+func ForEachAsset(ctx context.Context, assets []*AssetVuln, opts BatchOptions, fn func(ctx context.Context, a *AssetVuln) (interface{}, error)) ([]BatchResult, error) {
+	return RunBatch(ctx, len(assets), opts, func(ctx context.Context, i int) (interface{}, error) {
+		return fn(ctx, assets[i])
+	})
+}
+
+// ListFindingsForAssets calls ListAssetFindings for each of assets, at most
+// opts.Parallelism at once, and returns the findings keyed by asset ID. The
+// findings of the assets that succeeded are returned even when others
+// failed.
+func (s *ProjectsService) ListFindingsForAssets(ctx context.Context, projectID string, assets []*AssetVuln, opts BatchOptions) (map[string][]*FindingSummaryRecord, error) {
+	results, err := ForEachAsset(ctx, assets, opts, func(ctx context.Context, a *AssetVuln) (interface{}, error) {
+		r, _, err := s.ListAssetFindings(ctx, projectID, a.ID)
+		return r, err
+	})
+
+	findings := make(map[string][]*FindingSummaryRecord, len(results))
+	for _, res := range results {
+		if res.Err == nil {
+			findings[assets[res.Index].ID] = res.Value.([]*FindingSummaryRecord)
+		}
+	}
+	return findings, err
+}
@@ -0,0 +1,212 @@
+package nucleus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// cacheSweepInterval is how often expired entries are removed from a Cache.
+const cacheSweepInterval = time.Minute
+
+// Cache deduplicates and caches the GET requests of a Client.
+//
+// Identical GET requests made concurrently share a single round trip, and
+// successful responses are kept for the TTL of their operation, so that
+// calls such as GetProject and ListAssetGroups made from several goroutines
+// reach the API once. Any other request concerning a project, such as
+// UpdateAsset or CreateAsset, invalidates the entries of that project.
+// Streamed calls are neither deduplicated nor cached.
+//
+// A Cache is safe for concurrent use. It holds responses for the credentials
+// of one Client and must not be shared between clients.
+type Cache struct {
+	// TTL is how long responses are kept. Zero only deduplicates concurrent
+	// requests.
+	TTL time.Duration
+
+	// TTLs overrides TTL for the operations it lists, for example
+	// "Projects.GetProject". Requests sent with Client.Do use TTL.
+	TTLs map[string]time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	flights   map[string]*cacheFlight
+	projects  map[string]uint64 // invalidations of each project
+	purges    uint64
+	lastSweep time.Time
+}
+
+// cacheEntry is a stored response.
+type cacheEntry struct {
+	projectID string
+	resp      *http.Response
+	data      []byte
+	expires   time.Time
+}
+
+// cacheFlight is a GET request in progress, whose outcome is shared with the
+// identical requests made meanwhile.
+type cacheFlight struct {
+	done chan struct{}
+	resp *http.Response
+	data []byte
+	err  error
+}
+
+// fetchFunc sends req on behalf of call and reads the whole response body.
+// The body of the returned response is closed.
+type fetchFunc func(ctx context.Context, call *Call, req *http.Request) (*http.Response, []byte, error)
+
+// NewCache returns a Cache keeping successful GET responses for ttl.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{TTL: ttl}
+}
+
+// InvalidateProject removes the cached responses concerning projectID.
+// Responses to requests in progress are not stored either.
+func (c *Cache) InvalidateProject(projectID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(projectID)
+}
+
+// Purge removes every cached response. Responses to requests in progress are
+// not stored either.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+	c.purges++
+}
+
+// fetch returns the response to req, from the cache or from a request in
+// progress when possible, calling fetch otherwise.
+func (c *Cache) fetch(ctx context.Context, call *Call, req *http.Request, fetch fetchFunc) (*http.Response, []byte, bool, error) {
+	if req.Method != http.MethodGet {
+		// Invalidate even when the request fails, as it may still have
+		// modified the project.
+		resp, data, err := fetch(ctx, call, req)
+		if call.ProjectID != "" {
+			c.InvalidateProject(call.ProjectID)
+		}
+		return resp, data, false, err
+	}
+
+	key := req.URL.String()
+	for {
+		c.mu.Lock()
+		now := time.Now()
+		if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+			c.mu.Unlock()
+			return cachedResponse(e.resp, req), e.data, true, nil
+		}
+
+		if f, ok := c.flights[key]; ok {
+			c.mu.Unlock()
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				return nil, nil, false, ctx.Err()
+			}
+			if f.err != nil {
+				if isContextErr(f.err) && ctx.Err() == nil {
+					// The request was cancelled by its caller, not ours.
+					continue
+				}
+				return nil, nil, false, f.err
+			}
+			return cachedResponse(f.resp, req), f.data, true, nil
+		}
+
+		f := &cacheFlight{done: make(chan struct{})}
+		if c.flights == nil {
+			c.flights = map[string]*cacheFlight{}
+		}
+		c.flights[key] = f
+		gen, purges := c.projects[call.ProjectID], c.purges
+		c.mu.Unlock()
+
+		f.resp, f.data, f.err = fetch(ctx, call, req)
+
+		c.mu.Lock()
+		delete(c.flights, key)
+		ttl := c.ttl(call.Operation)
+		stale := c.projects[call.ProjectID] != gen || c.purges != purges
+		if f.err == nil && ttl > 0 && !stale && isSuccess(f.resp, f.data) {
+			c.store(key, &cacheEntry{
+				projectID: call.ProjectID,
+				resp:      f.resp,
+				data:      f.data,
+				expires:   time.Now().Add(ttl),
+			})
+		}
+		c.mu.Unlock()
+		close(f.done)
+
+		return f.resp, f.data, false, f.err
+	}
+}
+
+// ttl returns the TTL of operation. c.mu must be held.
+func (c *Cache) ttl(operation string) time.Duration {
+	if ttl, ok := c.TTLs[operation]; ok {
+		return ttl
+	}
+	return c.TTL
+}
+
+// store adds e to the cache under key, removing expired entries from time to
+// time. c.mu must be held.
+func (c *Cache) store(key string, e *cacheEntry) {
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= cacheSweepInterval {
+		for k, old := range c.entries {
+			if !now.Before(old.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	if c.entries == nil {
+		c.entries = map[string]*cacheEntry{}
+	}
+	c.entries[key] = e
+}
+
+// invalidate removes the entries of projectID and prevents the responses of
+// the requests for it in progress from being stored. c.mu must be held.
+func (c *Cache) invalidate(projectID string) {
+	for k, e := range c.entries {
+		if e.projectID == projectID {
+			delete(c.entries, k)
+		}
+	}
+	if c.projects == nil {
+		c.projects = map[string]uint64{}
+	}
+	c.projects[projectID]++
+}
+
+// cachedResponse returns a copy of resp, the response to an identical
+// request, as the response to req.
+func cachedResponse(resp *http.Response, req *http.Request) *http.Response {
+	r := *resp
+	r.Header = resp.Header.Clone()
+	r.Request = req
+	return &r
+}
+
+// isSuccess reports whether resp and its body data make a successful
+// response.
+func isSuccess(resp *http.Response, data []byte) bool {
+	return checkResponse(resp, data) == nil
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,146 @@
+package nucleus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+	"github.com/rsclarke/go-nucleus/nucleus/nucleustest"
+)
+
+func TestCacheDeduplicates(t *testing.T) {
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		wantRequests int // after the concurrent calls and one more
+	}{
+		{name: "without TTL", ttl: 0, wantRequests: 2},
+		{name: "with TTL", ttl: time.Minute, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := nucleustest.NewServer()
+			defer srv.Close()
+			projectID := srv.Store.AddProject(nucleus.Project{Name: "p"})
+			srv.InjectFault(nucleustest.Fault{Path: "projects/*", Times: 1, Latency: 100 * time.Millisecond})
+
+			client, counter := countingClient(srv, nucleus.WithCache(nucleus.NewCache(tt.ttl)))
+			ctx := context.Background()
+
+			const calls = 5
+			var wg sync.WaitGroup
+			cached := make([]bool, calls)
+			for i := 0; i < calls; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					project, resp, err := client.Projects.GetProject(ctx, projectID)
+					if err != nil || project.ID != projectID {
+						t.Errorf("GetProject = %+v, %v", project, err)
+						return
+					}
+					cached[i] = resp.Cached
+				}(i)
+			}
+			wg.Wait()
+
+			var shared int
+			for _, c := range cached {
+				if c {
+					shared++
+				}
+			}
+			if got := counter.count(); got != 1 || shared != calls-1 {
+				t.Errorf("sent %d requests with %d cached responses, want 1 and %d", got, shared, calls-1)
+			}
+
+			_, resp, err := client.Projects.GetProject(ctx, projectID)
+			if err != nil {
+				t.Fatalf("GetProject error = %v", err)
+			}
+			if got := counter.count(); got != tt.wantRequests || resp.Cached != (tt.ttl > 0) {
+				t.Errorf("sent %d requests, Cached = %v, want %d and %v", got, resp.Cached, tt.wantRequests, tt.ttl > 0)
+			}
+		})
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		all        bool // whether other projects are invalidated too
+		invalidate func(ctx context.Context, c *nucleus.Client, cache *nucleus.Cache, projectID, assetID string) error
+	}{
+		{
+			name: "UpdateAsset",
+			invalidate: func(ctx context.Context, c *nucleus.Client, _ *nucleus.Cache, projectID, assetID string) error {
+				_, _, err := c.Projects.UpdateAsset(ctx, projectID, &nucleus.Asset{ID: assetID, Name: "renamed"})
+				return err
+			},
+		},
+		{
+			name: "InvalidateProject",
+			invalidate: func(_ context.Context, _ *nucleus.Client, cache *nucleus.Cache, projectID, _ string) error {
+				cache.InvalidateProject(projectID)
+				return nil
+			},
+		},
+		{
+			name: "Purge",
+			all:  true,
+			invalidate: func(_ context.Context, _ *nucleus.Client, cache *nucleus.Cache, _, _ string) error {
+				cache.Purge()
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := nucleustest.NewServer()
+			defer srv.Close()
+			projectID := srv.Store.AddProject(nucleus.Project{Name: "p"})
+			assetID := srv.Store.AddAsset(projectID, nucleus.Asset{Name: "a"})
+			otherID := srv.Store.AddProject(nucleus.Project{Name: "q"})
+
+			cache := nucleus.NewCache(time.Minute)
+			client, counter := countingClient(srv, nucleus.WithCache(cache))
+			ctx := context.Background()
+
+			get := func() bool {
+				t.Helper()
+				_, resp, err := client.Projects.GetAsset(ctx, projectID, assetID)
+				if err != nil {
+					t.Fatalf("GetAsset error = %v", err)
+				}
+				return resp.Cached
+			}
+			getOther := func() bool {
+				t.Helper()
+				_, resp, err := client.Projects.GetProject(ctx, otherID)
+				if err != nil {
+					t.Fatalf("GetProject error = %v", err)
+				}
+				return resp.Cached
+			}
+
+			get()
+			getOther()
+			if cached := get(); !cached {
+				t.Error("second GetAsset not cached")
+			}
+
+			if err := tt.invalidate(ctx, client, cache, projectID, assetID); err != nil {
+				t.Fatalf("invalidate error = %v", err)
+			}
+			sent := counter.count()
+			if cached := get(); cached || counter.count() != sent+1 {
+				t.Error("GetAsset after invalidation served from the cache")
+			}
+			if cached := getOther(); cached == tt.all {
+				t.Errorf("GetProject of another project Cached = %v", cached)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package nucleus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+)
+
+// Cassette holds HTTP interactions recorded by a RecordingTransport and
+// served back by a ReplayTransport.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and the response it received.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the recorded part of an http.Request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the recorded part of an http.Response.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// LoadCassette reads a cassette file written by RecordingTransport.Save.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Cassette)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("decoding cassette %v: %v", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cassette to path as indented JSON.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// RecordingTransport is an http.RoundTripper that records every request and
+// response passing through it so that they can be saved to a cassette. The
+// x-apikey header and other credentials are always scrubbed, along with the
+// values of ScrubFields in JSON bodies. To capture authenticated requests it
+// should be the Transport of an APIKeyTransport.
+type RecordingTransport struct {
+	Transport http.RoundTripper
+
+	// ScrubHeaders and ScrubFields list further headers and JSON fields
+	// whose values are replaced in the recording.
+	ScrubHeaders []string
+	ScrubFields  []string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody := requestBody(req)
+
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := bufferBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: t.scrubHeader(req.Header),
+			Body:   string(t.scrubBody(reqBody)),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     t.scrubHeader(resp.Header),
+			Body:       string(t.scrubBody(respBody)),
+		},
+	}
+
+	t.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, i)
+	t.mu.Unlock()
+
+	return resp, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far.
+func (t *RecordingTransport) Cassette() *Cassette {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &Cassette{Interactions: append([]*Interaction(nil), t.cassette.Interactions...)}
+}
+
+// Save writes the interactions recorded so far to the cassette file path.
+func (t *RecordingTransport) Save(path string) error {
+	return t.Cassette().Save(path)
+}
+
+// Client returns an *http.Client that records the requests it makes.
+func (t *RecordingTransport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *RecordingTransport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (t *RecordingTransport) scrubHeader(h http.Header) http.Header {
+	h = redactHeader(h)
+	for k := range h {
+		if containsFold(t.ScrubHeaders, k) {
+			h[k] = []string{redacted}
+		}
+	}
+	return h
+}
+
+func (t *RecordingTransport) scrubBody(data []byte) []byte {
+	var v interface{}
+	if len(t.ScrubFields) == 0 || json.Unmarshal(data, &v) != nil {
+		return data
+	}
+	b, err := json.Marshal(redactValue(v, t.ScrubFields))
+	if err != nil {
+		return data
+	}
+	return b
+}
+
+// ReplayTransport is an http.RoundTripper that answers requests with the
+// responses of a cassette instead of sending them. A request matches an
+// interaction with the same method, path and query parameters; the host is
+// ignored so that a cassette recorded against one base URL can be replayed
+// against another. Interactions are served in the order they were recorded,
+// the last match being repeated once all have been served.
+type ReplayTransport struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	served   []bool
+}
+
+// NewReplayTransport returns a ReplayTransport serving the interactions of
+// the cassette file at path.
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayTransportFromCassette(c), nil
+}
+
+// NewReplayTransportFromCassette returns a ReplayTransport serving the
+// interactions of c.
+func NewReplayTransportFromCassette(c *Cassette) *ReplayTransport {
+	return &ReplayTransport{cassette: c, served: make([]bool, len(c.Interactions))}
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	i, err := t.match(req)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header, len(i.Response.Header))
+	for k, v := range i.Response.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	// Scrubbing may have changed the length of the recorded body.
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(i.Response.Body))),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Client returns an *http.Client that replays the cassette.
+func (t *ReplayTransport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *ReplayTransport) match(req *http.Request) (*Interaction, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := -1
+	for n, i := range t.cassette.Interactions {
+		if !interactionMatches(i, req) {
+			continue
+		}
+		if !t.served[n] {
+			t.served[n] = true
+			return i, nil
+		}
+		last = n
+	}
+	if last >= 0 {
+		return t.cassette.Interactions[last], nil
+	}
+	return nil, fmt.Errorf("nucleus: no recorded interaction for %v %v", req.Method, req.URL)
+}
+
+func interactionMatches(i *Interaction, req *http.Request) bool {
+	if i.Request.Method != req.Method {
+		return false
+	}
+	u, err := url.Parse(i.Request.URL)
+	if err != nil || u.Path != req.URL.Path {
+		return false
+	}
+	return reflect.DeepEqual(u.Query(), req.URL.Query())
+}
@@ -0,0 +1,294 @@
+package nucleus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched through errors.Is by the CircuitOpenError
+// returned for requests short-circuited by an open CircuitBreaker.
+var ErrCircuitOpen = errors.New("nucleus: circuit breaker is open")
+
+// CircuitOpenError is returned instead of sending a request while the
+// CircuitBreaker of the Client is open.
+type CircuitOpenError struct {
+	// Until is when the breaker half-opens and lets trial requests through.
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v until %v", ErrCircuitOpen, e.Until.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+// The states of a CircuitBreaker. A closed breaker sends every request, an
+// open one none, and a half-open one a few trial requests deciding whether
+// it closes again.
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// Defaults applied to the zero fields of a CircuitBreaker.
+const (
+	defaultCircuitFailureRatio = 0.5
+	defaultCircuitWindow       = time.Minute
+	defaultCircuitMinRequests  = 10
+	defaultCircuitCoolDown     = 30 * time.Second
+)
+
+// CircuitBreaker stops a Client from sending requests while the API is
+// failing. It opens once the ratio of failed requests reaches FailureRatio,
+// after which requests fail immediately with a *CircuitOpenError. After
+// CoolDown it half-opens and lets HalfOpenRequests trial requests through:
+// if they all succeed it closes again, otherwise it reopens.
+//
+// Each attempt of a retried request counts separately, and retries stop as
+// soon as the breaker opens. Requests cancelled by their context, and those
+// failing with a *CredentialsError, are not counted. A CircuitBreaker is safe
+// for concurrent use and may be shared between clients.
+type CircuitBreaker struct {
+	// FailureRatio is the fraction of failed requests, between 0 and 1,
+	// that opens the breaker, 0.5 if not positive.
+	FailureRatio float64
+
+	// MinRequests is the number of requests that must have been counted
+	// before the breaker may open, 10 if not positive.
+	MinRequests int
+
+	// Window is the period over which requests are counted while the
+	// breaker is closed, one minute if not positive. The counts start over
+	// at the end of each period.
+	Window time.Duration
+
+	// CoolDown is how long the breaker stays open before half-opening,
+	// 30 seconds if not positive.
+	CoolDown time.Duration
+
+	// HalfOpenRequests is the number of trial requests let through when the
+	// breaker is half-open, 1 if not positive.
+	HalfOpenRequests int
+
+	// IsFailure, if set, decides whether the response or error of a request
+	// counts as a failure. By default network errors, 429 Too Many Requests
+	// and 5xx responses do.
+	IsFailure func(resp *http.Response, err error) bool
+
+	// OnStateChange, if set, is called after every change of state, for
+	// example to raise an alert when the breaker opens. It must not block.
+	OnStateChange func(from, to CircuitState)
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openUntil   time.Time
+	trials      int
+	successes   int
+}
+
+// NewCircuitBreaker returns a CircuitBreaker opening when at least
+// failureRatio of the requests fail, and half-opening after coolDown.
+func NewCircuitBreaker(failureRatio float64, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureRatio: failureRatio,
+		CoolDown:     coolDown,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	from, to := b.advance(time.Now())
+	state := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return state
+}
+
+// allow reports whether a request may be sent, returning the generation the
+// outcome of the request must be recorded against, or a *CircuitOpenError.
+func (b *CircuitBreaker) allow() (uint64, error) {
+	if b == nil {
+		return 0, nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	from, to := b.advance(now)
+	var err error
+	switch b.state {
+	case CircuitOpen:
+		err = &CircuitOpenError{Until: b.openUntil}
+	case CircuitHalfOpen:
+		if b.trials >= b.halfOpenRequests() {
+			err = &CircuitOpenError{Until: now}
+		} else {
+			b.trials++
+		}
+	}
+	gen := b.generation
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return gen, err
+}
+
+// record counts the outcome of a request allowed in generation gen. Outcomes
+// of requests allowed before the last change of state are ignored, as are
+// those of requests cancelled by their context or failing for lack of
+// credentials.
+func (b *CircuitBreaker) record(gen uint64, req *http.Request, resp *http.Response, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	from, to := b.advance(now)
+	if gen == b.generation {
+		// Requests not sent for lack of credentials say nothing of the API.
+		cancelled := err != nil && (req.Context().Err() != nil || isCredentialsError(err))
+		failed := !cancelled && b.isFailure(resp, err)
+
+		switch b.state {
+		case CircuitClosed:
+			if !cancelled {
+				b.requests++
+				if failed {
+					b.failures++
+				}
+				if b.failures > 0 && b.requests >= b.minRequests() &&
+					float64(b.failures) >= b.failureRatio()*float64(b.requests) {
+					from, to = b.setState(CircuitOpen, now)
+				}
+			}
+		case CircuitHalfOpen:
+			switch {
+			case cancelled:
+				// Give the trial to another request.
+				b.trials--
+			case failed:
+				from, to = b.setState(CircuitOpen, now)
+			default:
+				b.successes++
+				if b.successes >= b.halfOpenRequests() {
+					from, to = b.setState(CircuitClosed, now)
+				}
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// advance half-opens the breaker once its cool-down is over and starts a new
+// counting window when the current one has ended. It returns the change of
+// state, if any. b.mu must be held.
+func (b *CircuitBreaker) advance(now time.Time) (from, to CircuitState) {
+	switch b.state {
+	case CircuitClosed:
+		if now.Sub(b.windowStart) >= b.window() {
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+	case CircuitOpen:
+		if !now.Before(b.openUntil) {
+			return b.setState(CircuitHalfOpen, now)
+		}
+	}
+	return b.state, b.state
+}
+
+// setState moves the breaker to state and resets its counts. b.mu must be
+// held.
+func (b *CircuitBreaker) setState(state CircuitState, now time.Time) (from, to CircuitState) {
+	from = b.state
+	b.state = state
+	b.generation++
+	b.windowStart = now
+	b.requests, b.failures = 0, 0
+	b.trials, b.successes = 0, 0
+	if state == CircuitOpen {
+		b.openUntil = now.Add(b.coolDown())
+	}
+	return from, state
+}
+
+func (b *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+func (b *CircuitBreaker) isFailure(resp *http.Response, err error) bool {
+	if b.IsFailure != nil {
+		return b.IsFailure(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func (b *CircuitBreaker) failureRatio() float64 {
+	if b.FailureRatio > 0 {
+		return b.FailureRatio
+	}
+	return defaultCircuitFailureRatio
+}
+
+func (b *CircuitBreaker) window() time.Duration {
+	if b.Window > 0 {
+		return b.Window
+	}
+	return defaultCircuitWindow
+}
+
+func (b *CircuitBreaker) minRequests() int {
+	if b.MinRequests > 0 {
+		return b.MinRequests
+	}
+	return defaultCircuitMinRequests
+}
+
+func (b *CircuitBreaker) coolDown() time.Duration {
+	if b.CoolDown > 0 {
+		return b.CoolDown
+	}
+	return defaultCircuitCoolDown
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.HalfOpenRequests > 0 {
+		return b.HalfOpenRequests
+	}
+	return 1
+}
@@ -0,0 +1,121 @@
+package nucleus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+	"github.com/rsclarke/go-nucleus/nucleus/nucleustest"
+)
+
+// stateRecorder records the state changes of a CircuitBreaker.
+type stateRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (r *stateRecorder) record(from, to nucleus.CircuitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, from.String()+" -> "+to.String())
+}
+
+func (r *stateRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.changes...)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	const coolDown = 50 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		trialStatus int // status of the trial request, 0 for success
+		wantChanges []string
+		wantState   nucleus.CircuitState
+	}{
+		{
+			name:        "closes after a successful trial",
+			wantChanges: []string{"closed -> open", "open -> half-open", "half-open -> closed"},
+			wantState:   nucleus.CircuitClosed,
+		},
+		{
+			name:        "reopens after a failed trial",
+			trialStatus: http.StatusBadGateway,
+			wantChanges: []string{"closed -> open", "open -> half-open", "half-open -> open"},
+			wantState:   nucleus.CircuitOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := nucleustest.NewServer()
+			defer srv.Close()
+			srv.InjectFault(nucleustest.ServerError(2, http.StatusServiceUnavailable))
+
+			var changes stateRecorder
+			breaker := &nucleus.CircuitBreaker{
+				MinRequests:   2,
+				CoolDown:      coolDown,
+				OnStateChange: changes.record,
+			}
+			client, counter := countingClient(srv, nucleus.WithCircuitBreaker(breaker))
+			ctx := context.Background()
+
+			for i := 0; i < 2; i++ {
+				if _, _, err := client.Projects.ListProjects(ctx); !nucleus.IsServerError(err) {
+					t.Fatalf("ListProjects #%d error = %v, want a server error", i+1, err)
+				}
+			}
+			if got := breaker.State(); got != nucleus.CircuitOpen {
+				t.Fatalf("State() after 2 failures = %v, want open", got)
+			}
+
+			_, _, err := client.Projects.ListProjects(ctx)
+			if !errors.Is(err, nucleus.ErrCircuitOpen) {
+				t.Errorf("ListProjects while open error = %v, want ErrCircuitOpen", err)
+			}
+			if got := counter.count(); got != 2 {
+				t.Errorf("sent %d requests, want 2", got)
+			}
+
+			time.Sleep(coolDown + 10*time.Millisecond)
+			if tt.trialStatus != 0 {
+				srv.InjectFault(nucleustest.ServerError(1, tt.trialStatus))
+			}
+			_, _, err = client.Projects.ListProjects(ctx)
+			if (err != nil) != (tt.trialStatus != 0) {
+				t.Errorf("trial ListProjects error = %v", err)
+			}
+
+			if got := breaker.State(); got != tt.wantState {
+				t.Errorf("State() = %v, want %v", got, tt.wantState)
+			}
+			if got := changes.get(); !reflect.DeepEqual(got, tt.wantChanges) {
+				t.Errorf("state changes = %q, want %q", got, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresCancelled(t *testing.T) {
+	srv := nucleustest.NewServer()
+	defer srv.Close()
+	srv.InjectFault(nucleustest.Slow(time.Second))
+
+	breaker := &nucleus.CircuitBreaker{MinRequests: 1}
+	client := srv.Client(nucleus.WithCircuitBreaker(breaker))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.Projects.ListProjects(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ListProjects error = %v, want context.DeadlineExceeded", err)
+	}
+	if got := breaker.State(); got != nucleus.CircuitClosed {
+		t.Errorf("State() = %v, want closed", got)
+	}
+}
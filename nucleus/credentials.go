@@ -0,0 +1,289 @@
+package nucleus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAPIKeyEnv is the environment variable read by EnvProvider by
+	// default.
+	DefaultAPIKeyEnv = "NUCLEUS_API_KEY"
+
+	// DefaultProfileEnv is the environment variable naming the profile
+	// read by ConfigFileProvider by default.
+	DefaultProfileEnv = "NUCLEUS_PROFILE"
+
+	defaultProfile = "default"
+	apiKeyField    = "api_key"
+)
+
+// ErrNoCredentials is returned by a CredentialsProvider that has no API key
+// to offer, letting a ChainProvider move on to the next provider.
+var ErrNoCredentials = errors.New("nucleus: no credentials found")
+
+// CredentialsError is the error of a request that was not sent because the
+// CredentialsProvider of its APIKeyTransport failed. It is not retried and
+// does not count as a failure of the API for a CircuitBreaker.
+type CredentialsError struct {
+	Err error
+}
+
+func (e *CredentialsError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the provider, as in
+// errors.Is(err, ErrNoCredentials).
+func (e *CredentialsError) Unwrap() error {
+	return e.Err
+}
+
+func isCredentialsError(err error) bool {
+	var credErr *CredentialsError
+	return errors.As(err, &credErr)
+}
+
+// CredentialsProvider supplies the API key used to authenticate requests.
+// It is called for every request so that rotated keys are picked up without
+// restarting, and must be safe for concurrent use.
+type CredentialsProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// StaticProvider always returns the same API key.
+type StaticProvider string
+
+// APIKey implements CredentialsProvider.
+func (p StaticProvider) APIKey(ctx context.Context) (string, error) {
+	if p == "" {
+		return "", ErrNoCredentials
+	}
+	return string(p), nil
+}
+
+// EnvProvider reads the API key from an environment variable.
+type EnvProvider struct {
+	// Var is the name of the variable, DefaultAPIKeyEnv if empty.
+	Var string
+}
+
+// APIKey implements CredentialsProvider.
+func (p *EnvProvider) APIKey(ctx context.Context) (string, error) {
+	name := p.Var
+	if name == "" {
+		name = DefaultAPIKeyEnv
+	}
+	key := strings.TrimSpace(os.Getenv(name))
+	if key == "" {
+		return "", fmt.Errorf("%w: %v is not set", ErrNoCredentials, name)
+	}
+	return key, nil
+}
+
+// FileProvider reads the API key from a file holding nothing but the key, such
+// as a Kubernetes secret mounted as a volume. The file is read again whenever
+// its modification time or size changes.
+type FileProvider struct {
+	Path string
+
+	mu      sync.Mutex
+	cache   fileCache
+	current string
+}
+
+// APIKey implements CredentialsProvider.
+func (p *FileProvider) APIKey(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, changed, err := p.cache.read(p.Path)
+	if err != nil {
+		return "", err
+	}
+	if changed {
+		p.current = string(bytes.TrimSpace(data))
+	}
+	if p.current == "" {
+		return "", fmt.Errorf("%w: %v is empty", ErrNoCredentials, p.Path)
+	}
+	return p.current, nil
+}
+
+// ConfigFileProvider reads the API key from a credentials file holding named
+// profiles, for example one per organisation:
+//
+//	[default]
+//	api_key = 0123456789abcdef
+//
+//	[acme]
+//	api_key = fedcba9876543210
+//
+// Lines starting with # or ; are comments. The file is read again whenever
+// it changes.
+type ConfigFileProvider struct {
+	// Path is the credentials file, DefaultCredentialsPath() if empty.
+	Path string
+
+	// Profile is the section to read, the value of DefaultProfileEnv or
+	// "default" if empty.
+	Profile string
+
+	mu       sync.Mutex
+	cache    fileCache
+	profiles map[string]map[string]string
+}
+
+// DefaultCredentialsPath returns the credentials file read by
+// ConfigFileProvider by default, $XDG_CONFIG_HOME/nucleus/credentials, or
+// ~/.config/nucleus/credentials if XDG_CONFIG_HOME is not set, on every
+// platform.
+func DefaultCredentialsPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "nucleus", "credentials")
+}
+
+// APIKey implements CredentialsProvider.
+func (p *ConfigFileProvider) APIKey(ctx context.Context) (string, error) {
+	path := p.Path
+	if path == "" {
+		path = DefaultCredentialsPath()
+	}
+	profile := p.Profile
+	if profile == "" {
+		profile = os.Getenv(DefaultProfileEnv)
+	}
+	if profile == "" {
+		profile = defaultProfile
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, changed, err := p.cache.read(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %v does not exist", ErrNoCredentials, path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if changed {
+		profiles, err := parseProfiles(data)
+		if err != nil {
+			p.cache = fileCache{}
+			return "", fmt.Errorf("parsing %v: %v", path, err)
+		}
+		p.profiles = profiles
+	}
+
+	key := p.profiles[profile][apiKeyField]
+	if key == "" {
+		return "", fmt.Errorf("%w: no %v for profile %q in %v", ErrNoCredentials, apiKeyField, profile, path)
+	}
+	return key, nil
+}
+
+// parseProfiles parses the INI-style sections of a credentials file.
+func parseProfiles(data []byte) (map[string]map[string]string, error) {
+	profiles := map[string]map[string]string{}
+	var section map[string]string
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if profiles[name] == nil {
+				profiles[name] = map[string]string{}
+			}
+			section = profiles[name]
+		default:
+			i := strings.IndexByte(line, '=')
+			if i < 0 || section == nil {
+				return nil, fmt.Errorf("line %d: expected a [profile] or key = value", n)
+			}
+			section[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return profiles, sc.Err()
+}
+
+// ChainProvider tries each of its providers in turn, returning the first API
+// key found. Providers returning ErrNoCredentials are skipped; any other
+// error stops the chain.
+type ChainProvider []CredentialsProvider
+
+// APIKey implements CredentialsProvider.
+func (c ChainProvider) APIKey(ctx context.Context) (string, error) {
+	var reasons []string
+	for _, p := range c {
+		key, err := p.APIKey(ctx)
+		if err == nil {
+			return key, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			return "", err
+		}
+		reasons = append(reasons, strings.TrimPrefix(err.Error(), ErrNoCredentials.Error()+": "))
+	}
+	if len(reasons) == 0 {
+		return "", ErrNoCredentials
+	}
+	return "", fmt.Errorf("%w: %v", ErrNoCredentials, strings.Join(reasons, "; "))
+}
+
+// DefaultCredentials returns a chain reading the API key from the
+// NUCLEUS_API_KEY environment variable, then from the given profile of the
+// default credentials file.
+func DefaultCredentials(profile string) CredentialsProvider {
+	return ChainProvider{
+		&EnvProvider{},
+		&ConfigFileProvider{Profile: profile},
+	}
+}
+
+// fileCache holds the contents of a file, read again only when its
+// modification time or size changes.
+type fileCache struct {
+	path    string
+	modTime time.Time
+	size    int64
+	data    []byte
+}
+
+// read returns the contents of the file at path and whether they changed
+// since the last call.
+func (c *fileCache) read(path string) ([]byte, bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if c.data != nil && c.path == path && fi.ModTime().Equal(c.modTime) && fi.Size() == c.size {
+		return c.data, false, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	*c = fileCache{path: path, modTime: fi.ModTime(), size: fi.Size(), data: data}
+	return data, true, nil
+}
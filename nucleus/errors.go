@@ -0,0 +1,101 @@
+package nucleus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matched by ErrorResponse and RateLimitError through
+// errors.Is, according to the HTTP status code of the response.
+var (
+	ErrNotFound     = errors.New("nucleus: not found")
+	ErrUnauthorized = errors.New("nucleus: unauthorized")
+	ErrForbidden    = errors.New("nucleus: forbidden")
+	ErrRateLimited  = errors.New("nucleus: rate limited")
+	ErrServer       = errors.New("nucleus: server error")
+)
+
+// Is reports whether target is the sentinel error corresponding to the
+// status code of the response, or to Code for error envelopes sent with a
+// 2xx status.
+func (r *ErrorResponse) Is(target error) bool {
+	if r.Response == nil {
+		return false
+	}
+	switch code := r.statusCode(); {
+	case code == http.StatusNotFound:
+		return target == ErrNotFound
+	case code == http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case code == http.StatusForbidden:
+		return target == ErrForbidden
+	case code == http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case code >= 500:
+		return target == ErrServer
+	}
+	return false
+}
+
+// statusCode returns the status code of the error: that of the response,
+// unless it is a 2xx one, in which case the error envelope carries the real
+// one in Code.
+func (r *ErrorResponse) statusCode() int {
+	code := r.Response.StatusCode
+	if 200 <= code && code <= 299 && r.Code != 0 {
+		return r.Code
+	}
+	return code
+}
+
+// RateLimitError is returned when the API responds with 429 Too Many Requests.
+type RateLimitError struct {
+	ErrorResponse *ErrorResponse
+
+	// RetryAfter is the delay requested by the Retry-After header, zero if
+	// the API did not send one.
+	RetryAfter time.Duration
+}
+
+func newRateLimitError(r *ErrorResponse) *RateLimitError {
+	d, _ := parseRetryAfter(r.Response)
+	return &RateLimitError{ErrorResponse: r, RetryAfter: d}
+}
+
+func (r *RateLimitError) Error() string {
+	return fmt.Sprintf("%v (retry after %v)", r.ErrorResponse.Error(), r.RetryAfter)
+}
+
+// Unwrap returns the underlying ErrorResponse.
+func (r *RateLimitError) Unwrap() error {
+	return r.ErrorResponse
+}
+
+// IsNotFound reports whether err was caused by a 404 Not Found response.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsUnauthorized reports whether err was caused by a 401 Unauthorized
+// response, usually due to a missing or invalid API key.
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsForbidden reports whether err was caused by a 403 Forbidden response.
+func IsForbidden(err error) bool {
+	return errors.Is(err, ErrForbidden)
+}
+
+// IsRateLimited reports whether err was caused by a 429 Too Many Requests
+// response.
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
+// IsServerError reports whether err was caused by a 5xx response.
+func IsServerError(err error) bool {
+	return errors.Is(err, ErrServer)
+}
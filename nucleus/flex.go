@@ -0,0 +1,303 @@
+package nucleus
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The Flex types decode the values that the API sends in more than one
+// shape, usually a string standing in for another type, such as "" for an
+// empty list. Values of any other shape are reported with a
+// *json.UnmarshalTypeError rather than silently decoded as zero, so that
+// models of other endpoints can be built with them consistently.
+//
+// As with encoding/json, null leaves a FlexInt, FlexFloat or FlexBool
+// unchanged and sets a FlexSlice or FlexMap to nil.
+
+// FlexInt is an integer sent by the API as a JSON number or, more often, as a
+// numeric string, such as the finding counts of an asset. An empty string
+// decodes as zero.
+//
+// A FlexInt keeps the JSON it was decoded from, which String returns unquoted
+// and MarshalJSON returns as is, so that fields that used to be strings keep
+// their string form, as in a.FindingCountCritical.String(), and are sent back
+// to the API as received. Once Value is changed, or for a FlexInt not decoded
+// from the API, they return Value in decimal, encoded as a numeric string.
+type FlexInt struct {
+	// Value is the integer.
+	Value int64
+
+	// raw is the JSON the value was decoded from, and received the value
+	// it decoded to.
+	raw      string
+	received int64
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *FlexInt) UnmarshalJSON(data []byte) error {
+	if jsonType(data) == jsonNull {
+		return nil
+	}
+	s, ok := flexNumber(data)
+	if !ok {
+		return flexError(data, n)
+	}
+	var i int64
+	if s != "" {
+		var err error
+		i, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			// Accept integral values written with a fraction or exponent.
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt64 {
+				return flexError(data, n)
+			}
+			i = int64(f)
+		}
+	}
+	*n = FlexInt{Value: i, raw: string(bytes.TrimSpace(data)), received: i}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n FlexInt) MarshalJSON() ([]byte, error) {
+	if n.raw != "" && n.Value == n.received {
+		return []byte(n.raw), nil
+	}
+	return []byte(strconv.Quote(n.String())), nil
+}
+
+// String returns the text n was decoded from, or Value in decimal.
+func (n FlexInt) String() string {
+	if n.raw != "" && n.Value == n.received {
+		return flexText(n.raw)
+	}
+	return strconv.FormatInt(n.Value, 10)
+}
+
+// isEmpty reports whether n is zero and was not decoded from a number.
+func (n FlexInt) isEmpty() bool {
+	return n.Value == 0 && (n.raw == "" || n.raw == `""`)
+}
+
+// FlexFloat is a decimal number sent by the API as a JSON number or as a
+// numeric string, such as a score. It is decoded and encoded like a FlexInt,
+// and its String returns Value with as few digits as needed once changed.
+type FlexFloat struct {
+	// Value is the number.
+	Value float64
+
+	// raw is the JSON the value was decoded from, and received the value
+	// it decoded to.
+	raw      string
+	received float64
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *FlexFloat) UnmarshalJSON(data []byte) error {
+	if jsonType(data) == jsonNull {
+		return nil
+	}
+	s, ok := flexNumber(data)
+	if !ok {
+		return flexError(data, f)
+	}
+	var v float64
+	if s != "" {
+		var err error
+		v, err = strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return flexError(data, f)
+		}
+	}
+	*f = FlexFloat{Value: v, raw: string(bytes.TrimSpace(data)), received: v}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (f FlexFloat) MarshalJSON() ([]byte, error) {
+	if f.raw != "" && f.Value == f.received {
+		return []byte(f.raw), nil
+	}
+	return []byte(strconv.Quote(f.String())), nil
+}
+
+// String returns the text f was decoded from, or Value in decimal.
+func (f FlexFloat) String() string {
+	if f.raw != "" && f.Value == f.received {
+		return flexText(f.raw)
+	}
+	return strconv.FormatFloat(f.Value, 'f', -1, 64)
+}
+
+// isEmpty reports whether f is zero and was not decoded from a number.
+func (f FlexFloat) isEmpty() bool {
+	return f.Value == 0 && (f.raw == "" || f.raw == `""`)
+}
+
+// FlexBool is a boolean sent by the API as a JSON boolean, as a string such
+// as "true", "false", "1" or "0", or as the number 1 or 0. An empty string
+// decodes as false. A FlexBool is encoded as a JSON boolean.
+type FlexBool bool
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	s := string(data)
+	switch {
+	case s == "null":
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return flexError(data, b)
+		}
+		s = strings.TrimSpace(unquoted)
+		if s == "" {
+			*b = false
+			return nil
+		}
+	case s != "true" && s != "false" && s != "1" && s != "0":
+		return flexError(data, b)
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return flexError(data, b)
+	}
+	*b = FlexBool(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b FlexBool) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bool(b))
+}
+
+// FlexSlice is a list of strings sent by the API as a JSON array, or as a
+// string when it has no more than one element, such as the groups of an
+// asset: an empty string decodes as an empty list and any other string as a
+// list of that string. A FlexSlice is encoded as a JSON array, empty when
+// nil.
+type FlexSlice []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *FlexSlice) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch jsonType(data) {
+	case jsonNull:
+		*s = nil
+	case jsonString:
+		var v string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*s = nil
+		if v != "" {
+			*s = FlexSlice{v}
+		}
+	case jsonArray:
+		var v []string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*s = v
+	default:
+		return flexError(data, s)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s FlexSlice) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(s))
+}
+
+// FlexMap is a JSON object sent by the API as an empty string or an empty
+// array when it has no fields, such as the info of an asset. A FlexMap is
+// encoded as a JSON object, empty when nil.
+type FlexMap map[string]interface{}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *FlexMap) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch jsonType(data) {
+	case jsonNull:
+		*m = nil
+	case jsonString:
+		if string(data) != `""` {
+			return flexError(data, m)
+		}
+		*m = nil
+	case jsonArray:
+		var v []json.RawMessage
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		if len(v) > 0 {
+			return flexError(data, m)
+		}
+		*m = nil
+	case jsonObject:
+		var v map[string]interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*m = v
+	default:
+		return flexError(data, m)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m FlexMap) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]interface{}(m))
+}
+
+// flexNumber returns the text of the number data holds, unquoted and without
+// surrounding spaces. It returns false if data holds neither a number nor a
+// string.
+func flexNumber(data []byte) (string, bool) {
+	data = bytes.TrimSpace(data)
+	switch jsonType(data) {
+	case jsonNumber:
+		return string(data), true
+	case jsonString:
+		s, err := strconv.Unquote(string(data))
+		if err != nil {
+			return "", false
+		}
+		return strings.TrimSpace(s), true
+	}
+	return "", false
+}
+
+// flexText returns the text of raw, a JSON number or string.
+func flexText(raw string) string {
+	if s, err := strconv.Unquote(raw); err == nil {
+		return s
+	}
+	return raw
+}
+
+// flexError returns the error of decoding data into v, a pointer to one of
+// the Flex types.
+func flexError(data []byte, v interface{}) error {
+	value := jsonType(data)
+	switch value {
+	case jsonArray, jsonObject:
+	default:
+		value += " " + string(data)
+	}
+	return &json.UnmarshalTypeError{Value: value, Type: reflect.TypeOf(v).Elem()}
+}
@@ -0,0 +1,231 @@
+package nucleus_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+)
+
+func TestFlexMap(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    nucleus.FlexMap
+		wantErr bool
+	}{
+		{in: `{"a":"b"}`, want: nucleus.FlexMap{"a": "b"}},
+		{in: `{}`, want: nucleus.FlexMap{}},
+		{in: `""`, want: nil},
+		{in: `[]`, want: nil},
+		{in: `null`, want: nil},
+		{in: `"x"`, wantErr: true},
+		{in: `[1]`, wantErr: true},
+		{in: `1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		m := nucleus.FlexMap{"old": true}
+		err := json.Unmarshal([]byte(tt.in), &m)
+		if tt.wantErr {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Errorf("Unmarshal(%s) error = %v, want *json.UnmarshalTypeError", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(m, tt.want) {
+			t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.in, m, tt.want)
+		}
+	}
+}
+
+func TestAssetVulnEmptyInfo(t *testing.T) {
+	var assets []*nucleus.AssetVuln
+	data := `[{"asset_id":"1","asset_info":[]},{"asset_id":"2","asset_info":""},{"asset_id":"3","asset_info":{"os":"linux"}}]`
+	if err := json.Unmarshal([]byte(data), &assets); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if len(assets) != 3 || assets[0].Info != nil || assets[1].Info != nil || assets[2].Info["os"] != "linux" {
+		t.Errorf("Unmarshal = %+v", assets)
+	}
+}
+
+func TestFlexInt(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		str     string
+		wantErr bool
+	}{
+		{in: `"7"`, want: 7, str: "7"},
+		{in: `"07"`, want: 7, str: "07"},
+		{in: `7`, want: 7, str: "7"},
+		{in: `"-3"`, want: -3, str: "-3"},
+		{in: `"7.0"`, want: 7, str: "7.0"},
+		{in: `1e2`, want: 100, str: "1e2"},
+		{in: `""`, want: 0, str: ""},
+		{in: `"7.5"`, wantErr: true},
+		{in: `"x"`, wantErr: true},
+		{in: `true`, wantErr: true},
+		{in: `[]`, wantErr: true},
+		{in: `{}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var n nucleus.FlexInt
+		err := json.Unmarshal([]byte(tt.in), &n)
+		if tt.wantErr {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Errorf("Unmarshal(%s) error = %v, want *json.UnmarshalTypeError", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		if n.Value != tt.want || n.String() != tt.str {
+			t.Errorf("Unmarshal(%s) = %d (%q), want %d (%q)", tt.in, n.Value, n.String(), tt.want, tt.str)
+		}
+		if out, _ := json.Marshal(n); string(out) != tt.in {
+			t.Errorf("Marshal(Unmarshal(%s)) = %s", tt.in, out)
+		}
+	}
+
+	n := nucleus.FlexInt{Value: 5}
+	if err := json.Unmarshal([]byte(`null`), &n); err != nil || n.Value != 5 {
+		t.Errorf("Unmarshal(null) = %d, %v, want 5 unchanged", n.Value, err)
+	}
+	var changed nucleus.FlexInt
+	_ = json.Unmarshal([]byte(`"07"`), &changed)
+	changed.Value++
+	if out, _ := json.Marshal(changed); string(out) != `"8"` || changed.String() != "8" {
+		t.Errorf("Marshal of a changed FlexInt = %s (%q), want \"8\"", out, changed.String())
+	}
+}
+
+func TestFlexFloat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		str     string
+		wantErr bool
+	}{
+		{in: `"7.50"`, want: 7.5, str: "7.50"},
+		{in: `7.5`, want: 7.5, str: "7.5"},
+		{in: `"10"`, want: 10, str: "10"},
+		{in: `""`, want: 0, str: ""},
+		{in: `"NaN"`, wantErr: true},
+		{in: `"x"`, wantErr: true},
+		{in: `false`, wantErr: true},
+		{in: `[1]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var f nucleus.FlexFloat
+		err := json.Unmarshal([]byte(tt.in), &f)
+		if tt.wantErr {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Errorf("Unmarshal(%s) error = %v, want *json.UnmarshalTypeError", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		if f.Value != tt.want || f.String() != tt.str {
+			t.Errorf("Unmarshal(%s) = %v (%q), want %v (%q)", tt.in, f.Value, f.String(), tt.want, tt.str)
+		}
+		if out, _ := json.Marshal(f); string(out) != tt.in {
+			t.Errorf("Marshal(Unmarshal(%s)) = %s", tt.in, out)
+		}
+	}
+
+	if out, _ := json.Marshal(nucleus.FlexFloat{Value: 2.25}); string(out) != `"2.25"` {
+		t.Errorf("Marshal(FlexFloat{2.25}) = %s", out)
+	}
+}
+
+func TestFlexBool(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    nucleus.FlexBool
+		wantErr bool
+	}{
+		{in: `true`, want: true},
+		{in: `false`, want: false},
+		{in: `"true"`, want: true},
+		{in: `"0"`, want: false},
+		{in: `1`, want: true},
+		{in: `""`, want: false},
+		{in: `"yes"`, wantErr: true},
+		{in: `2`, wantErr: true},
+		{in: `[]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		b := !tt.want
+		err := json.Unmarshal([]byte(tt.in), &b)
+		if tt.wantErr {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Errorf("Unmarshal(%s) error = %v, want *json.UnmarshalTypeError", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || b != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", tt.in, b, err, tt.want)
+		}
+	}
+
+	b := nucleus.FlexBool(true)
+	if err := json.Unmarshal([]byte(`null`), &b); err != nil || !b {
+		t.Errorf("Unmarshal(null) = %v, %v, want true unchanged", b, err)
+	}
+	if out, _ := json.Marshal(nucleus.FlexBool(true)); string(out) != `true` {
+		t.Errorf("Marshal(FlexBool(true)) = %s", out)
+	}
+}
+
+func TestFlexSlice(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    nucleus.FlexSlice
+		wantErr bool
+	}{
+		{in: `["a","b"]`, want: nucleus.FlexSlice{"a", "b"}},
+		{in: `[]`, want: nucleus.FlexSlice{}},
+		{in: `"a"`, want: nucleus.FlexSlice{"a"}},
+		{in: `""`, want: nil},
+		{in: `null`, want: nil},
+		{in: `[1]`, wantErr: true},
+		{in: `{}`, wantErr: true},
+		{in: `1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		s := nucleus.FlexSlice{"old"}
+		err := json.Unmarshal([]byte(tt.in), &s)
+		if tt.wantErr {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Errorf("Unmarshal(%s) error = %v, want *json.UnmarshalTypeError", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(s, tt.want) {
+			t.Errorf("Unmarshal(%s) = %#v, %v, want %#v", tt.in, s, err, tt.want)
+		}
+	}
+
+	if out, _ := json.Marshal(nucleus.FlexSlice(nil)); string(out) != `[]` {
+		t.Errorf("Marshal(nil FlexSlice) = %s", out)
+	}
+	if out, _ := json.Marshal(nucleus.FlexMap(nil)); string(out) != `{}` {
+		t.Errorf("Marshal(nil FlexMap) = %s", out)
+	}
+}
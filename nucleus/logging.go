@@ -0,0 +1,131 @@
+package nucleus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxLoggedBodySize is the number of bytes of a body included in a log
+	// entry.
+	maxLoggedBodySize = 4096
+
+	redacted = "REDACTED"
+)
+
+// defaultRedactFields are the JSON fields redacted from logged bodies by
+// default.
+var defaultRedactFields = []string{"asset_notes"}
+
+// redactedHeaders are never logged with their actual values.
+var redactedHeaders = []string{"X-Apikey", "Authorization", "Cookie", "Set-Cookie"}
+
+// Logger receives structured debug log entries from a Client. The arguments
+// after msg alternate between string keys and values, so a *slog.Logger or a
+// zap SugaredLogger's Debugw can be used with little or no adapting.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+}
+
+// logAttempt logs a single attempt at sending req. reqBody is the request
+// body captured before sending, if LogBodies is set.
+func (c *Client) logAttempt(req *http.Request, reqBody []byte, resp *http.Response, err error, attempt int, latency time.Duration) {
+	kv := []interface{}{
+		"method", req.Method,
+		"url", req.URL.String(),
+		"attempt", attempt,
+		"latency", latency,
+		"request_headers", redactHeader(req.Header),
+	}
+	if c.LogBodies && reqBody != nil {
+		kv = append(kv, "request_body", c.redactBody(reqBody))
+	}
+	if err != nil {
+		kv = append(kv, "error", err.Error())
+		c.Logger.Debug("nucleus: request failed", kv...)
+		return
+	}
+
+	kv = append(kv, "status", resp.StatusCode, "response_headers", redactHeader(resp.Header))
+	if c.LogBodies {
+		if data, err := bufferBody(resp); err == nil {
+			kv = append(kv, "response_body", c.redactBody(data))
+		}
+	}
+	c.Logger.Debug("nucleus: request sent", kv...)
+}
+
+// requestBody returns a copy of the body of req without consuming it.
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, _ := ioutil.ReadAll(body)
+	return data
+}
+
+// bufferBody reads the body of resp and replaces it with an in-memory copy so
+// that it can still be decoded. It is only used for logging as it defeats
+// streaming.
+func bufferBody(resp *http.Response) ([]byte, error) {
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// redactHeader returns a copy of h with the values of sensitive headers
+// replaced.
+func redactHeader(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, v := range h {
+		if containsFold(redactedHeaders, k) {
+			h2[k] = []string{redacted}
+			continue
+		}
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
+}
+
+// redactBody returns data as a string with the values of c.RedactFields
+// replaced, truncated to maxLoggedBodySize.
+func (c *Client) redactBody(data []byte) string {
+	var v interface{}
+	if len(c.RedactFields) > 0 && json.Unmarshal(data, &v) == nil {
+		if b, err := json.Marshal(redactValue(v, c.RedactFields)); err == nil {
+			data = b
+		}
+	}
+	if len(data) > maxLoggedBodySize {
+		return string(data[:maxLoggedBodySize]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+// redactValue replaces the values of fields in the JSON objects within v.
+func redactValue(v interface{}, fields []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if containsFold(fields, k) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactValue(e, fields)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = redactValue(e, fields)
+		}
+	}
+	return v
+}
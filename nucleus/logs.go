@@ -2,7 +2,6 @@ package nucleus
 
 import (
 	"context"
-	"net/http"
 	"strconv"
 )
 
@@ -11,8 +10,22 @@ type LogsService service
 
 // Log represents an entry in the audit log
 type Log struct {
-	Details  string `json:"details"`
-	Datetime string `json:"datetime"`
+	RawJSON
+
+	Details  string    `json:"details"`
+	Datetime Timestamp `json:"datetime"`
+}
+
+// UnmarshalJSON decodes a log entry, keeping its JSON in RawJSON.
+func (l *Log) UnmarshalJSON(data []byte) error {
+	type log Log
+	return unmarshalModel(data, (*log)(l), &l.RawJSON)
+}
+
+// MarshalJSON encodes a log entry with the unmodelled fields in Extra.
+func (l Log) MarshalJSON() ([]byte, error) {
+	type log Log
+	return marshalModel(log(l), l.RawJSON)
 }
 
 // LogRequest options to limit requested audit log events
@@ -24,7 +37,7 @@ type LogRequest struct {
 }
 
 // GetAuditLogs returns log events for the given time period given in the logRequest
-func (s *LogsService) GetAuditLogs(ctx context.Context, logRequest LogRequest) ([]*Log, *http.Response, error) {
+func (s *LogsService) GetAuditLogs(ctx context.Context, logRequest LogRequest) ([]*Log, *Response, error) {
 	req, err := s.client.NewRequest("GET", "logs", nil)
 	if err != nil {
 		return nil, nil, err
@@ -43,10 +56,64 @@ func (s *LogsService) GetAuditLogs(ctx context.Context, logRequest LogRequest) (
 	req.URL.RawQuery = q.Encode()
 
 	var l []*Log
-	resp, err := s.client.Do(ctx, req, &l)
+	resp, err := s.client.call(ctx, &Call{Operation: "Logs.GetAuditLogs", Request: req, Result: &l})
 	if err != nil {
 		return nil, resp, err
 	}
+	resp.populatePageValues(logRequest.Start, logRequest.Limit, len(l))
 
 	return l, resp, nil
 }
+
+// LogIterator walks every audit log event matched by a LogRequest, fetching
+// pages lazily as Next is called.
+type LogIterator struct {
+	pager
+	page []*Log
+}
+
+// Next advances the iterator to the next event, returning false when there
+// are no more events or an error occurred.
+func (it *LogIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current event.
+func (it *LogIterator) Value() *Log {
+	if it.pos < 0 || it.pos >= len(it.page) {
+		return nil
+	}
+	return it.page[it.pos]
+}
+
+// GetAuditLogsIterator returns an iterator over all log events matching
+// logRequest. logRequest.Start is the offset of the first event and
+// logRequest.Limit the page size used for each call to GetAuditLogs.
+func (s *LogsService) GetAuditLogsIterator(ctx context.Context, logRequest LogRequest) *LogIterator {
+	it := new(LogIterator)
+	it.pager = newPager(ctx, logRequest.Start, logRequest.Limit, func(ctx context.Context, start, limit int64) (int, error) {
+		r := logRequest
+		r.Start, r.Limit = start, limit
+		l, _, err := s.GetAuditLogs(ctx, r)
+		if err != nil {
+			return 0, err
+		}
+		it.page = l
+		return len(l), nil
+	})
+	return it
+}
+
+// GetAllAuditLogs returns all log events matching logRequest, following pages
+// until the API has no more to return.
+func (s *LogsService) GetAllAuditLogs(ctx context.Context, logRequest LogRequest) ([]*Log, error) {
+	var all []*Log
+	it := s.GetAuditLogsIterator(ctx, logRequest)
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
@@ -0,0 +1,74 @@
+package nucleus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of the API calls made by a Client.
+// Implementations must be safe for concurrent use. The operation is the name
+// of the service method, as in Call.Operation.
+type Metrics interface {
+	// RequestCompleted is called once per API call with the status code of
+	// the final response, zero if none was received, and the time taken
+	// including any retries.
+	RequestCompleted(operation string, statusCode int, latency time.Duration)
+
+	// RequestRetried is called before each retry of a request, attempt
+	// being the number of the attempt about to be made.
+	RequestRetried(operation string, attempt int)
+
+	// BytesReceived is called with the number of bytes of each response
+	// body read.
+	BytesReceived(operation string, n int64)
+}
+
+// instrument wraps h to report the outcome of each call to c.Metrics.
+func (c *Client) instrument(h Handler) Handler {
+	if c.Metrics == nil {
+		return h
+	}
+	return func(ctx context.Context, call *Call) (*Response, error) {
+		start := time.Now()
+		resp, err := h(ctx, call)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.Metrics.RequestCompleted(call.Operation, status, time.Since(start))
+		return resp, err
+	}
+}
+
+// countingBody reports the number of bytes read from a response body to
+// Metrics when it is closed.
+type countingBody struct {
+	io.ReadCloser
+	metrics   Metrics
+	operation string
+	n         int64
+	once      sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.once.Do(func() { b.metrics.BytesReceived(b.operation, b.n) })
+	return b.ReadCloser.Close()
+}
+
+// countBody arranges for the bytes read from the body of resp to be reported
+// to c.Metrics.
+func (c *Client) countBody(op string, resp *http.Response) {
+	if c.Metrics == nil || resp == nil {
+		return
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, metrics: c.Metrics, operation: op}
+}
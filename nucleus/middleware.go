@@ -0,0 +1,66 @@
+package nucleus
+
+import (
+	"context"
+	"net/http"
+)
+
+// Call describes a single API call as it passes through the middleware
+// chain of a Client.
+type Call struct {
+	// Operation names the service method making the call, for example
+	// "Projects.ListAssets". It is empty for requests sent directly with
+	// Client.Do or Client.DoStream.
+	Operation string
+
+	// ProjectID and AssetID identify the project and asset the call
+	// concerns, if any.
+	ProjectID string
+	AssetID   string
+
+	// Request is the HTTP request to send. Middleware may modify it, for
+	// example to add headers, before calling the next Handler.
+	Request *http.Request
+
+	// Result is the value the response body is decoded into, which holds
+	// the decoded result once the next Handler has returned. It is nil for
+	// streamed calls and calls discarding the body.
+	Result interface{}
+
+	// Attempts is the number of times the request was sent, retries
+	// included, once the next Handler has returned.
+	Attempts int
+}
+
+// Handler performs an API call.
+type Handler func(ctx context.Context, call *Call) (*Response, error)
+
+// Middleware wraps a Handler to run code before and after an API call.
+type Middleware func(next Handler) Handler
+
+// invoke runs h for call through the middleware of c. The first middleware
+// in c.Middleware is the outermost.
+func (c *Client) invoke(ctx context.Context, call *Call, h Handler) (*Response, error) {
+	if ctx == nil {
+		return nil, errNilContext
+	}
+	h = c.trace(c.instrument(h))
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		h = c.Middleware[i](h)
+	}
+	return h(ctx, call)
+}
+
+// call runs call through the middleware of c, then sends its request and
+// decodes the response into call.Result.
+func (c *Client) call(ctx context.Context, call *Call) (*Response, error) {
+	return c.invoke(ctx, call, c.roundTrip)
+}
+
+// callStream runs call through the middleware of c, then sends its request
+// and decodes each element of the response with fn.
+func (c *Client) callStream(ctx context.Context, call *Call, fn DecodeFunc) (*Response, error) {
+	return c.invoke(ctx, call, func(ctx context.Context, call *Call) (*Response, error) {
+		return c.stream(ctx, call, fn)
+	})
+}
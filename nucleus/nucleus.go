@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const (
@@ -18,6 +19,8 @@ const (
 	userAgent         = "go-nucleus"
 )
 
+var errNilContext = errors.New("context must be non-nil")
+
 // Client for Nucleus Security API
 type Client struct {
 	client *http.Client
@@ -26,6 +29,47 @@ type Client struct {
 
 	UserAgent string
 
+	// RetryPolicy controls whether and how failed requests are retried.
+	// A nil RetryPolicy sends each request exactly once.
+	RetryPolicy *RetryPolicy
+
+	// RateLimiter, if set, limits the rate of requests sent by every
+	// service. Retries are also subject to the limit.
+	RateLimiter *RateLimiter
+
+	// CircuitBreaker, if set, stops requests from being sent while the
+	// API is failing, returning a *CircuitOpenError instead.
+	CircuitBreaker *CircuitBreaker
+
+	// Cache, if set, deduplicates concurrent identical GET requests and
+	// caches their responses.
+	Cache *Cache
+
+	// StrictDecoding, if set, reports the differences between responses
+	// and the models they are decoded into.
+	StrictDecoding *StrictDecoding
+
+	// Middleware is run around every API call, the first being the
+	// outermost.
+	Middleware []Middleware
+
+	// Logger, if set, receives a debug entry for every HTTP request sent,
+	// with the values of API key and other credential headers redacted.
+	Logger Logger
+
+	// LogBodies adds request and response bodies to the log entries, which
+	// requires buffering responses in memory. The values of the JSON fields
+	// listed in RedactFields are replaced in the logged bodies.
+	LogBodies    bool
+	RedactFields []string
+
+	// Metrics, if set, receives measurements of every API call.
+	Metrics Metrics
+
+	// Tracer, if set, creates a span around every API call and propagates
+	// the trace context in the request headers.
+	Tracer Tracer
+
 	common service
 
 	Projects *ProjectsService
@@ -38,6 +82,8 @@ type service struct {
 
 // NewClient returns a new Nucleus Security API Client.
 // If httpClient is nil, a new http.Client is created.
+// Use NewClientWithOptions to reach other base URLs or to have the
+// configuration validated.
 func NewClient(organisation string, httpClient *http.Client) *Client {
 	if httpClient == nil {
 		httpClient = &http.Client{}
@@ -45,7 +91,16 @@ func NewClient(organisation string, httpClient *http.Client) *Client {
 
 	baseURL, _ := url.Parse(fmt.Sprintf(defaultBaseURLFmt, organisation))
 
-	c := &Client{client: httpClient, BaseURL: baseURL, UserAgent: userAgent}
+	return newClient(baseURL, httpClient)
+}
+
+func newClient(baseURL *url.URL, httpClient *http.Client) *Client {
+	c := &Client{
+		client:       httpClient,
+		BaseURL:      baseURL,
+		UserAgent:    userAgent,
+		RedactFields: append([]string(nil), defaultRedactFields...),
+	}
 	c.common.client = c
 
 	c.Projects = (*ProjectsService)(&c.common)
@@ -90,12 +145,23 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 	return req, nil
 }
 
+// maxErrorBodySize is the number of bytes of the response body kept in an
+// ErrorResponse.
+const maxErrorBodySize = 1024
+
 // ErrorResponse reports an error caused by the API request.
+// Use errors.Is with ErrNotFound, ErrUnauthorized, etc. to test its cause.
 type ErrorResponse struct {
 	Response *http.Response
 	Success  bool   `json:"success"`
 	Code     int    `json:"code"`
 	Message  string `json:"message"`
+
+	// ContentType and Body hold the content type and the first bytes of the
+	// raw response body, which may not be JSON when the error comes from a
+	// proxy or load balancer.
+	ContentType string `json:"-"`
+	Body        []byte `json:"-"`
 }
 
 func (r *ErrorResponse) Error() string {
@@ -104,65 +170,226 @@ func (r *ErrorResponse) Error() string {
 		r.Success, r.Code, r.Message)
 }
 
-// Do sends API request and returns http.Response
-func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
-	if ctx == nil {
-		return nil, errors.New("context must be non-nil")
+// Do sends API request and returns Response
+func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, error) {
+	return c.call(ctx, &Call{Request: req, Result: v})
+}
+
+// roundTrip is the innermost Handler of Do, sending call.Request and decoding
+// the response into call.Result.
+func (c *Client) roundTrip(ctx context.Context, call *Call) (*Response, error) {
+	req := call.Request.WithContext(ctx)
+	v := call.Result
+
+	start := time.Now()
+	var (
+		resp   *http.Response
+		data   []byte
+		cached bool
+		err    error
+	)
+	if c.Cache != nil {
+		resp, data, cached, err = c.Cache.fetch(ctx, call, req, c.fetch)
+	} else {
+		resp, data, err = c.fetch(ctx, call, req)
+	}
+	if resp == nil {
+		return nil, err
 	}
-	req = req.WithContext(ctx)
 
-	resp, err := c.client.Do(req)
+	response := newResponse(resp)
+	response.Cached = cached
+	defer func() { response.Elapsed = time.Since(start) }()
+
 	if err != nil {
-		// If we got an error, and the context has been canceled,
-		// the context's error is probably more useful.
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			return nil, err
+		return response, err
+	}
+
+	if err := checkResponse(resp, data); err != nil {
+		return response, err
+	}
+
+	// On success, decode in to v if given
+	if v != nil && len(bytes.TrimSpace(data)) > 0 {
+		if c.StrictDecoding != nil {
+			err = c.StrictDecoding.decode(call, data, v)
+		} else {
+			err = json.Unmarshal(data, v)
 		}
 	}
+	return response, err
+}
 
+// fetch sends req on behalf of call and reads the whole response body,
+// returning the response with its body closed.
+func (c *Client) fetch(ctx context.Context, call *Call, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := c.send(ctx, call, req)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer resp.Body.Close()
 
 	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return resp, err
+	return resp, data, err
+}
+
+// checkResponse returns an error if resp has a status code outside the 2xx
+// range, or if data is an error envelope with success set to false, which
+// the API sometimes sends with a 200 status. Any non-2xx response results in
+// an ErrorResponse, whether or not its body could be decoded.
+func checkResponse(resp *http.Response, data []byte) error {
+	c := resp.StatusCode
+	success := 200 <= c && c <= 299
+	if success && !isErrorEnvelope(data) {
+		return nil
 	}
 
-	// On success, decode in to v if given
-	if c := resp.StatusCode; 200 <= c && c <= 299 {
+	errResp := &ErrorResponse{
+		Response:    resp,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        truncateBody(data),
+	}
+	// The body may be HTML, empty or of another shape altogether, in which
+	// case the fields keep their zero values.
+	_ = json.Unmarshal(data, errResp)
+	code := errResp.statusCode()
+	if errResp.Message == "" {
+		errResp.Message = http.StatusText(code)
+	}
 
-		if v != nil && data != nil {
-			decErr := json.Unmarshal(data, v)
-			if decErr == io.EOF {
-				decErr = nil
+	if code == http.StatusTooManyRequests {
+		return newRateLimitError(errResp)
+	}
+	return errResp
+}
+
+// isErrorEnvelope reports whether data is a JSON object with success set to
+// false.
+func isErrorEnvelope(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' || !bytes.Contains(data, []byte(`"success"`)) {
+		return false
+	}
+	var env struct {
+		Success *bool `json:"success"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return false
+	}
+	return env.Success != nil && !*env.Success
+}
+
+func truncateBody(data []byte) []byte {
+	if len(data) > maxErrorBodySize {
+		data = data[:maxErrorBodySize]
+	}
+	return append([]byte(nil), data...)
+}
+
+// send performs the round trip for req on behalf of call, retrying according
+// to c.RetryPolicy and counting the attempts in call.Attempts.
+func (c *Client) send(ctx context.Context, call *Call, req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		call.Attempts = attempt
+		// An open breaker rejects requests without waiting for the rate
+		// limiter.
+		gen, err := c.CircuitBreaker.allow()
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+		if c.RateLimiter != nil {
+			if err := c.RateLimiter.Wait(ctx); err != nil {
+				c.CircuitBreaker.record(gen, req, nil, err)
+				closeBody(req)
+				return nil, err
 			}
-			err = decErr
 		}
-		return resp, err
-	}
 
-	// On error, decode into ErrorResponse
-	errResp := &ErrorResponse{Response: resp}
-	if data != nil {
-		err = json.Unmarshal(data, errResp)
+		var reqBody []byte
+		if c.Logger != nil && c.LogBodies {
+			reqBody = requestBody(req)
+		}
+
+		start := time.Now()
+		resp, err := c.client.Do(req)
+		c.CircuitBreaker.record(gen, req, resp, err)
+		c.countBody(call.Operation, resp)
+		if c.Logger != nil {
+			c.logAttempt(req, reqBody, resp, err, attempt, time.Since(start))
+		}
+		c.RateLimiter.observe(resp)
 		if err != nil {
+			// If we got an error, and the context has been canceled,
+			// the context's error is probably more useful.
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+		}
+
+		retry, checkErr := c.RetryPolicy.shouldRetry(ctx, req, resp, err, attempt)
+		if checkErr != nil {
+			if resp != nil {
+				drainBody(resp)
+			}
+			return nil, checkErr
+		}
+		if !retry {
+			return resp, err
+		}
+
+		wait, ok := c.RetryPolicy.backoff(attempt, resp)
+		if !ok {
 			return resp, err
 		}
+		if resp != nil {
+			drainBody(resp)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		if err := rewindBody(req); err != nil {
+			return nil, err
+		}
+		if c.Metrics != nil {
+			c.Metrics.RequestRetried(call.Operation, attempt+1)
+		}
+	}
+}
+
+// closeBody closes the body of a request that will not be sent, as
+// http.Client.Do would have.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
 	}
-	return resp, errResp
 }
 
 // APIKeyTransport is an http.RoundTripper that authenticates all requests
-// by adding the x-apikey header with the provided value.
+// by adding the x-apikey header with the provided value. If Credentials is
+// set, the key is instead obtained from it for every request, and its errors
+// are returned as a *CredentialsError.
 type APIKeyTransport struct {
-	APIKey    string
-	Transport http.RoundTripper
+	APIKey      string
+	Credentials CredentialsProvider
+	Transport   http.RoundTripper
 }
 
 // RoundTrip implements the RoundTripper interface.
 func (t *APIKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	apiKey := t.APIKey
+	if t.Credentials != nil {
+		key, err := t.Credentials.APIKey(req.Context())
+		if err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, &CredentialsError{Err: err}
+		}
+		apiKey = key
+	}
 
 	req2 := new(http.Request)
 	*req2 = *req
@@ -172,7 +399,7 @@ func (t *APIKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		req2.Header[k] = append([]string(nil), s...)
 	}
 
-	req2.Header.Add("x-apikey", t.APIKey)
+	req2.Header.Add("x-apikey", apiKey)
 
 	return t.transport().RoundTrip(req2)
 }
@@ -0,0 +1,146 @@
+package nucleustest
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault describes an error injected into the responses of a Server. The
+// zero Fault matches every request and has no effect.
+type Fault struct {
+	// Method and Path select the requests affected. Path is relative to
+	// BasePath and may contain path.Match patterns, for example
+	// "projects/*/assets". Empty values match any request.
+	Method string
+	Path   string
+
+	// Times is the number of matching requests affected, after which the
+	// fault is removed. Zero affects every matching request.
+	Times int
+
+	// Latency delays the response, or the normal handling of the request
+	// if neither Status nor Body is set.
+	Latency time.Duration
+
+	// Status, if set, is sent instead of the normal response, with the API
+	// error envelope as body unless Body is set.
+	Status int
+
+	// RetryAfter, if set, is sent in the Retry-After header.
+	RetryAfter time.Duration
+
+	// Body, if set, is sent as is instead of the normal response body,
+	// for example to send malformed JSON or an HTML error page.
+	Body        string
+	ContentType string
+}
+
+// RateLimited returns a Fault answering the next n requests with 429 Too
+// Many Requests and the given Retry-After delay.
+func RateLimited(n int, retryAfter time.Duration) Fault {
+	return Fault{Times: n, Status: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+// ServerError returns a Fault answering the next n requests with status.
+func ServerError(n int, status int) Fault {
+	return Fault{Times: n, Status: status}
+}
+
+// MalformedJSON returns a Fault answering the next n requests with a
+// successful status and a truncated JSON body.
+func MalformedJSON(n int) Fault {
+	return Fault{Times: n, Status: http.StatusOK, Body: `[{"asset_id": `}
+}
+
+// Slow returns a Fault delaying every request by d.
+func Slow(d time.Duration) Fault {
+	return Fault{Latency: d}
+}
+
+// InjectFault adds f to the faults of the Server. When several faults match
+// a request, the first added is applied.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all the faults of the Server.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// applyFault applies the first fault matching r, reporting whether it wrote
+// the response.
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request) bool {
+	f := s.takeFault(r)
+	if f == nil {
+		return false
+	}
+
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-r.Context().Done():
+			return true
+		case <-t.C:
+		}
+	}
+	if f.Status == 0 && f.Body == "" {
+		return false
+	}
+
+	status := f.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+	}
+	if f.Body == "" {
+		writeError(w, status, http.StatusText(status))
+		return true
+	}
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(f.Body))
+	return true
+}
+
+// takeFault returns the first fault matching r, consuming one of its uses.
+func (s *Server) takeFault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rel := strings.Trim(strings.TrimPrefix(r.URL.Path, BasePath), "/")
+	for i, f := range s.faults {
+		if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+			continue
+		}
+		if f.Path != "" {
+			if ok, _ := path.Match(strings.Trim(f.Path, "/"), rel); !ok {
+				continue
+			}
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+// Package nucleustest provides an in-memory fake of the Nucleus Security API
+// for testing code that uses the nucleus package without a real tenant.
+package nucleustest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+)
+
+// BasePath is the path under which the Server serves the API, matching that
+// of the hosted service.
+const BasePath = "/nucleus/api/"
+
+// Server is a fake Nucleus API served over HTTP by an httptest.Server and
+// backed by a Store.
+type Server struct {
+	*httptest.Server
+
+	// Store holds the data served. It may be seeded at any time.
+	Store *Store
+
+	// APIKey, if set, is the value required in the x-apikey header of every
+	// request. Requests without it are answered with 401 Unauthorized.
+	APIKey string
+
+	mu     sync.Mutex
+	faults []*Fault
+}
+
+// NewServer starts and returns a new Server with an empty Store. The caller
+// should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{Store: NewStore()}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the base URL of the API served, with a trailing slash.
+func (s *Server) BaseURL() string {
+	return s.Server.URL + BasePath
+}
+
+// Client returns a nucleus.Client pointed at the Server and authenticated
+// with its APIKey, if any. opts are applied after those, so they may
+// override them.
+func (s *Server) Client(opts ...nucleus.ClientOption) *nucleus.Client {
+	base := []nucleus.ClientOption{
+		nucleus.WithBaseURL(s.BaseURL()),
+		nucleus.WithHTTPClient(s.Server.Client()),
+	}
+	if s.APIKey != "" {
+		base = append(base, nucleus.WithAPIKey(s.APIKey))
+	}
+	c, err := nucleus.NewClientWithOptions("", append(base, opts...)...)
+	if err != nil {
+		panic("nucleustest: " + err.Error())
+	}
+	return c
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.applyFault(w, r) {
+		return
+	}
+	if s.APIKey != "" && r.Header.Get("x-apikey") != s.APIKey {
+		writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, BasePath) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	s.route(w, r, strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, BasePath), "/"), "/"))
+}
+
+// route dispatches r on the segments of its path relative to BasePath.
+func (s *Server) route(w http.ResponseWriter, r *http.Request, seg []string) {
+	switch {
+	case match(r, seg, "GET", "logs"):
+		s.getLogs(w, r)
+	case match(r, seg, "GET", "projects"):
+		s.listProjects(w)
+	case match(r, seg, "GET", "projects", "*"):
+		s.getProject(w, seg[1])
+	case match(r, seg, "GET", "projects", "*", "assets"):
+		s.listAssets(w, r, seg[1])
+	case match(r, seg, "POST", "projects", "*", "assets"):
+		s.createAsset(w, r, seg[1])
+	case match(r, seg, "GET", "projects", "*", "assets", "groups"):
+		s.listAssetGroups(w, seg[1])
+	case match(r, seg, "GET", "projects", "*", "assets", "*"):
+		s.getAsset(w, seg[1], seg[3])
+	case match(r, seg, "PUT", "projects", "*", "assets", "*"):
+		s.updateAsset(w, r, seg[1], seg[3])
+	case match(r, seg, "GET", "projects", "*", "assets", "*", "findings"):
+		s.listAssetFindings(w, seg[1], seg[3])
+	case match(r, seg, "GET", "projects", "*", "connectors"):
+		s.listConnectors(w, seg[1])
+	case match(r, seg, "GET", "projects", "*", "assessments"):
+		s.listAssessments(w, seg[1])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// match reports whether r has the given method and its path segments match
+// pattern, where "*" matches any single segment.
+func match(r *http.Request, seg []string, method string, pattern ...string) bool {
+	if r.Method != method || len(seg) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != seg[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) listProjects(w http.ResponseWriter) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	projects := make([]nucleus.Project, 0, len(s.Store.projects))
+	for _, p := range s.Store.projects {
+		projects = append(projects, p.Project)
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) getProject(w http.ResponseWriter, projectID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	p := s.Store.project(projectID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.Project)
+}
+
+func (s *Server) listAssets(w http.ResponseWriter, r *http.Request, projectID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	p := s.Store.project(projectID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	q := r.URL.Query()
+	inactive, _ := strconv.ParseBool(q.Get("inactive_assets"))
+	var matched []*nucleus.Asset
+	for _, a := range p.assets {
+		if !inactive && !a.InactiveDate.IsZero() {
+			continue
+		}
+		if v := q.Get("ip_address"); v != "" && a.IPAddress != v {
+			continue
+		}
+		if v := q.Get("asset_name"); v != "" && a.Name != v {
+			continue
+		}
+		if v := q.Get("asset_name_ip"); v != "" && a.Name != v && a.IPAddress != v {
+			continue
+		}
+		matched = append(matched, a)
+	}
+
+	out := []map[string]interface{}{}
+	for _, a := range page(len(matched), q.Get("start"), q.Get("limit")) {
+		out = append(out, assetVuln(matched[a], p.findingCounts(matched[a].ID)))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// assetVuln renders a as returned by the list assets endpoint, which has the
+// asset's fields together with the counts of its findings by severity.
+func assetVuln(a *nucleus.Asset, counts map[string]int) map[string]interface{} {
+	m := map[string]interface{}{}
+	data, _ := json.Marshal(a)
+	_ = json.Unmarshal(data, &m)
+	if _, ok := m["asset_info"]; !ok {
+		// The API sends an empty string rather than an empty object.
+		m["asset_info"] = ""
+	}
+	for _, sev := range []string{"critical", "high", "medium", "low", "informational"} {
+		m["finding_count_"+sev] = strconv.Itoa(counts[sev])
+	}
+	return m
+}
+
+func (s *Server) createAsset(w http.ResponseWriter, r *http.Request, projectID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	p := s.Store.project(projectID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	a := new(nucleus.Asset)
+	if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.ID = s.Store.newID()
+	p.assets = append(p.assets, a)
+	writeJSON(w, http.StatusOK, nucleus.CreateAssetResponse{AssetID: a.ID, Success: true})
+}
+
+func (s *Server) listAssetGroups(w http.ResponseWriter, projectID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	p := s.Store.project(projectID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	groups := []nucleus.AssetGroup{}
+	for _, g := range p.assetGroups() {
+		groups = append(groups, nucleus.AssetGroup{Name: g})
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+func (s *Server) getAsset(w http.ResponseWriter, projectID, assetID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	a := s.Store.asset(projectID, assetID)
+	if a == nil {
+		writeError(w, http.StatusNotFound, "asset not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (s *Server) updateAsset(w http.ResponseWriter, r *http.Request, projectID, assetID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	a := s.Store.asset(projectID, assetID)
+	if a == nil {
+		writeError(w, http.StatusNotFound, "asset not found")
+		return
+	}
+	// Decoding over a copy of the stored asset only changes the fields sent.
+	updated := *a
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	updated.ID = assetID
+	*a = updated
+	writeJSON(w, http.StatusOK, nucleus.UpdateAssetResponse{AssetID: assetID, UnknownFields: []string{}, Success: true})
+}
+
+func (s *Server) listAssetFindings(w http.ResponseWriter, projectID, assetID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	p := s.Store.project(projectID)
+	if p == nil || s.Store.asset(projectID, assetID) == nil {
+		writeError(w, http.StatusNotFound, "asset not found")
+		return
+	}
+	findings := append([]nucleus.FindingSummaryRecord{}, p.findings[assetID]...)
+	writeJSON(w, http.StatusOK, findings)
+}
+
+func (s *Server) listConnectors(w http.ResponseWriter, projectID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	p := s.Store.project(projectID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, append([]nucleus.Connector{}, p.connectors...))
+}
+
+func (s *Server) listAssessments(w http.ResponseWriter, projectID string) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	p := s.Store.project(projectID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, append([]nucleus.Assessment{}, p.assessments...))
+}
+
+// getLogs serves the audit log. The since and after filters are accepted but
+// not applied.
+func (s *Server) getLogs(w http.ResponseWriter, r *http.Request) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	q := r.URL.Query()
+	logs := []nucleus.Log{}
+	for _, i := range page(len(s.Store.logs), q.Get("start"), q.Get("limit")) {
+		logs = append(logs, s.Store.logs[i])
+	}
+	writeJSON(w, http.StatusOK, logs)
+}
+
+// page returns the indexes of the items of a list of n items selected by the
+// start and limit query parameters. A missing or zero limit selects all
+// remaining items.
+func page(n int, start, limit string) []int {
+	from, _ := strconv.Atoi(start)
+	size, _ := strconv.Atoi(limit)
+	if from < 0 {
+		from = 0
+	}
+	to := n
+	if size > 0 && from+size < n {
+		to = from + size
+	}
+	var idx []int
+	for i := from; i < to; i++ {
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the error envelope sent by the API.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"success": false,
+		"code":    status,
+		"message": message,
+	})
+}
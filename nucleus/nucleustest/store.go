@@ -0,0 +1,216 @@
+package nucleustest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+)
+
+// Fixtures is a set of records loaded into a Store with Seed.
+type Fixtures struct {
+	Projects []ProjectFixture
+	Logs     []nucleus.Log
+}
+
+// ProjectFixture is a project together with its related records. Findings are
+// keyed by asset ID.
+type ProjectFixture struct {
+	Project     nucleus.Project
+	Assets      []nucleus.Asset
+	Findings    map[string][]nucleus.FindingSummaryRecord
+	Connectors  []nucleus.Connector
+	Assessments []nucleus.Assessment
+}
+
+// Store is the in-memory data behind a Server. It is safe for concurrent use,
+// so tests may seed and inspect it while the Server is running.
+type Store struct {
+	mu       sync.Mutex
+	projects []*project
+	logs     []nucleus.Log
+	nextID   int
+}
+
+type project struct {
+	nucleus.Project
+	assets      []*nucleus.Asset
+	findings    map[string][]nucleus.FindingSummaryRecord
+	connectors  []nucleus.Connector
+	assessments []nucleus.Assessment
+}
+
+// NewStore returns an empty Store.
+func NewStore() *Store {
+	return &Store{nextID: 1}
+}
+
+// Seed adds the records in f to the store. Records without an ID are
+// assigned one.
+func (s *Store) Seed(f Fixtures) {
+	for _, pf := range f.Projects {
+		id := s.AddProject(pf.Project)
+		for _, a := range pf.Assets {
+			s.AddAsset(id, a)
+		}
+		for assetID, findings := range pf.Findings {
+			for _, r := range findings {
+				s.AddFinding(id, assetID, r)
+			}
+		}
+		for _, c := range pf.Connectors {
+			s.AddConnector(id, c)
+		}
+		for _, a := range pf.Assessments {
+			s.AddAssessment(id, a)
+		}
+	}
+	for _, l := range f.Logs {
+		s.AddLog(l)
+	}
+}
+
+// AddProject adds p to the store and returns its ID.
+func (s *Store) AddProject(p nucleus.Project) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.ID == "" {
+		p.ID = s.newID()
+	}
+	s.projects = append(s.projects, &project{Project: p, findings: map[string][]nucleus.FindingSummaryRecord{}})
+	return p.ID
+}
+
+// AddAsset adds a to the project and returns its ID. It panics if the
+// project does not exist.
+func (s *Store) AddAsset(projectID string, a nucleus.Asset) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	if a.ID == "" {
+		a.ID = s.newID()
+	}
+	p.assets = append(p.assets, &a)
+	return a.ID
+}
+
+// AddFinding adds the finding r to an asset of the project. It panics if the
+// project does not exist.
+func (s *Store) AddFinding(projectID, assetID string, r nucleus.FindingSummaryRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	p.findings[assetID] = append(p.findings[assetID], r)
+}
+
+// AddConnector adds c to the project. It panics if the project does not
+// exist.
+func (s *Store) AddConnector(projectID string, c nucleus.Connector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	if c.ID == "" {
+		c.ID = s.newID()
+	}
+	p.connectors = append(p.connectors, c)
+}
+
+// AddAssessment adds a to the project. It panics if the project does not
+// exist.
+func (s *Store) AddAssessment(projectID string, a nucleus.Assessment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	a.ProjectID = projectID
+	p.assessments = append(p.assessments, a)
+}
+
+// AddLog appends l to the audit log.
+func (s *Store) AddLog(l nucleus.Log) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logs = append(s.logs, l)
+}
+
+// Asset returns a copy of an asset as currently stored, for example to check
+// the effect of UpdateAsset.
+func (s *Store) Asset(projectID, assetID string) (nucleus.Asset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a := s.asset(projectID, assetID); a != nil {
+		return *a, true
+	}
+	return nucleus.Asset{}, false
+}
+
+func (s *Store) newID() string {
+	id := strconv.Itoa(s.nextID)
+	s.nextID++
+	return id
+}
+
+// project returns the project with the given ID, or nil. s.mu must be held.
+func (s *Store) project(id string) *project {
+	for _, p := range s.projects {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Store) mustProject(id string) *project {
+	p := s.project(id)
+	if p == nil {
+		panic("nucleustest: unknown project " + id)
+	}
+	return p
+}
+
+// asset returns the asset with the given ID, or nil. s.mu must be held.
+func (s *Store) asset(projectID, assetID string) *nucleus.Asset {
+	p := s.project(projectID)
+	if p == nil {
+		return nil
+	}
+	for _, a := range p.assets {
+		if a.ID == assetID {
+			return a
+		}
+	}
+	return nil
+}
+
+// assetGroups returns the sorted names of the groups used by the assets of p.
+func (p *project) assetGroups() []string {
+	seen := map[string]bool{}
+	var groups []string
+	for _, a := range p.assets {
+		for _, g := range a.Groups {
+			if !seen[g] {
+				seen[g] = true
+				groups = append(groups, g)
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// findingCounts counts the findings of an asset by lower-cased severity.
+func (p *project) findingCounts(assetID string) map[string]int {
+	counts := map[string]int{}
+	for _, r := range p.findings[assetID] {
+		counts[strings.ToLower(r.Severity)]++
+	}
+	return counts
+}
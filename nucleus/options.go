@@ -0,0 +1,258 @@
+package nucleus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ClientOption configures a Client created by NewClientWithOptions.
+type ClientOption func(*clientConfig) error
+
+// clientConfig collects the options before the Client is built so that they
+// can be given in any order.
+type clientConfig struct {
+	baseURL     string
+	userAgent   string
+	httpClient  *http.Client
+	apiKey      string
+	credentials CredentialsProvider
+	timeout     time.Duration
+	retryPolicy *RetryPolicy
+	rateLimiter *RateLimiter
+	breaker     *CircuitBreaker
+	cache       *Cache
+	strict      *StrictDecoding
+	middleware  []Middleware
+	logger      Logger
+	logBodies   bool
+	metrics     Metrics
+	tracer      Tracer
+}
+
+// WithBaseURL sets the API base URL, for example that of an on-premise
+// Nucleus instance, instead of the one derived from the organisation.
+// A trailing slash is added when missing.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(cfg *clientConfig) error {
+		if baseURL == "" {
+			return errors.New("base URL must not be empty")
+		}
+		cfg.baseURL = baseURL
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.userAgent = ua
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http.Client used to send requests. The client is
+// copied, so options such as WithAPIKey and WithTimeout do not modify it.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(cfg *clientConfig) error {
+		if httpClient == nil {
+			return errors.New("http client must not be nil")
+		}
+		cfg.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithAPIKey authenticates every request with apiKey using an
+// APIKeyTransport wrapping the transport of the http.Client.
+func WithAPIKey(apiKey string) ClientOption {
+	return func(cfg *clientConfig) error {
+		if apiKey == "" {
+			return errors.New("API key must not be empty")
+		}
+		cfg.apiKey = apiKey
+		return nil
+	}
+}
+
+// WithCredentials authenticates every request with the API key supplied by
+// provider, which is consulted for each request so that keys can be rotated.
+func WithCredentials(provider CredentialsProvider) ClientOption {
+	return func(cfg *clientConfig) error {
+		if provider == nil {
+			return errors.New("credentials provider must not be nil")
+		}
+		cfg.credentials = provider
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit for each request made by the http.Client.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientConfig) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout must not be negative, got %v", timeout)
+		}
+		cfg.timeout = timeout
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the RetryPolicy of the Client.
+func WithRetryPolicy(policy *RetryPolicy) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithRateLimiter sets the RateLimiter of the Client.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.rateLimiter = limiter
+		return nil
+	}
+}
+
+// WithCircuitBreaker sets the CircuitBreaker of the Client.
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.breaker = breaker
+		return nil
+	}
+}
+
+// WithCache deduplicates and caches the GET requests of the Client with
+// cache.
+func WithCache(cache *Cache) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.cache = cache
+		return nil
+	}
+}
+
+// WithStrictDecoding reports the differences between responses and the
+// models they are decoded into as configured by strict.
+func WithStrictDecoding(strict *StrictDecoding) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.strict = strict
+		return nil
+	}
+}
+
+// WithMiddleware appends middleware to the chain run around every API call.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.middleware = append(cfg.middleware, middleware...)
+		return nil
+	}
+}
+
+// WithLogger enables debug logging of every request to logger. If
+// logBodies is true, request and response bodies are logged as well.
+func WithLogger(logger Logger, logBodies bool) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.logger = logger
+		cfg.logBodies = logBodies
+		return nil
+	}
+}
+
+// WithMetrics reports measurements of every API call to metrics.
+func WithMetrics(metrics Metrics) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.metrics = metrics
+		return nil
+	}
+}
+
+// WithTracer traces every API call with tracer.
+func WithTracer(tracer Tracer) ClientOption {
+	return func(cfg *clientConfig) error {
+		cfg.tracer = tracer
+		return nil
+	}
+}
+
+// NewClientWithOptions returns a new Nucleus Security API Client for the
+// organisation configured by opts. The organisation is only used to derive the
+// base URL and may be empty when WithBaseURL is given.
+func NewClientWithOptions(organisation string, opts ...ClientOption) (*Client, error) {
+	cfg := &clientConfig{userAgent: userAgent}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	rawURL := cfg.baseURL
+	if rawURL == "" {
+		if organisation == "" {
+			return nil, errors.New("organisation must not be empty without a base URL")
+		}
+		rawURL = fmt.Sprintf(defaultBaseURLFmt, organisation)
+	}
+	baseURL, err := parseBaseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{}
+	if cfg.httpClient != nil {
+		hc := *cfg.httpClient
+		httpClient = &hc
+	}
+	if cfg.timeout > 0 {
+		httpClient.Timeout = cfg.timeout
+	}
+	if cfg.apiKey != "" || cfg.credentials != nil {
+		httpClient.Transport = &APIKeyTransport{
+			APIKey:      cfg.apiKey,
+			Credentials: cfg.credentials,
+			Transport:   httpClient.Transport,
+		}
+	}
+
+	c := newClient(baseURL, httpClient)
+	c.UserAgent = cfg.userAgent
+	c.RetryPolicy = cfg.retryPolicy
+	c.RateLimiter = cfg.rateLimiter
+	c.CircuitBreaker = cfg.breaker
+	c.Cache = cfg.cache
+	c.StrictDecoding = cfg.strict
+	c.Middleware = cfg.middleware
+	c.Logger = cfg.logger
+	c.LogBodies = cfg.logBodies
+	c.Metrics = cfg.metrics
+	c.Tracer = cfg.tracer
+
+	return c, nil
+}
+
+// parseBaseURL parses and validates an API base URL, adding the trailing
+// slash required by NewRequest when it is missing.
+func parseBaseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %v", rawURL, err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("base URL %q must use the http or https scheme", rawURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("base URL %q has no host", rawURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("base URL %q must not have a query or fragment", rawURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		if u.RawPath != "" {
+			u.RawPath += "/"
+		}
+	}
+	return u, nil
+}
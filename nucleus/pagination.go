@@ -0,0 +1,81 @@
+package nucleus
+
+import "context"
+
+// defaultPageSize is used by the iterators when the request does not
+// specify a Limit.
+const defaultPageSize = 100
+
+// pageFetchFunc retrieves a single page of at most limit results starting at
+// the offset start and returns the number of items received.
+type pageFetchFunc func(ctx context.Context, start, limit int64) (int, error)
+
+// pager walks an endpoint paginated with start/limit offsets, fetching pages
+// lazily as the caller advances. Typed iterators embed a pager and keep the
+// items of the current page themselves, the fetch function being responsible
+// for storing them.
+type pager struct {
+	ctx   context.Context
+	fetch pageFetchFunc
+	start int64
+	limit int64
+
+	pos  int // index of the current item in the current page
+	n    int // number of items in the current page
+	done bool
+	err  error
+}
+
+func newPager(ctx context.Context, start, limit int64, fetch pageFetchFunc) pager {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if start < 0 {
+		start = 0
+	}
+	return pager{ctx: ctx, fetch: fetch, start: start, limit: limit, pos: -1}
+}
+
+// next advances to the next item, fetching another page once the current one
+// is exhausted. It reports false when there are no more items or an error
+// occurred.
+func (p *pager) next() bool {
+	if p.err != nil {
+		return false
+	}
+	if p.pos+1 < p.n {
+		p.pos++
+		return true
+	}
+	if p.done {
+		return false
+	}
+	if p.ctx == nil {
+		p.err = errNilContext
+		return false
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.err = err
+		return false
+	}
+
+	n, err := p.fetch(p.ctx, p.start, p.limit)
+	if err != nil {
+		p.err = err
+		p.n, p.pos = 0, -1
+		return false
+	}
+
+	p.start += int64(n)
+	p.n, p.pos = n, 0
+	// A short page means the endpoint has nothing further to return.
+	if int64(n) < p.limit {
+		p.done = true
+	}
+	return n > 0
+}
+
+// Err returns the first error encountered while iterating, if any.
+func (p *pager) Err() error {
+	return p.err
+}
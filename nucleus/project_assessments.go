@@ -3,7 +3,6 @@ package nucleus
 import (
 	"context"
 	"fmt"
-	"net/http"
 )
 
 // AssessmentContact contains the contact details of the assessor
@@ -33,15 +32,15 @@ type AssessmentVuln struct {
 
 // AssessmentActivity actions conducted by users
 type AssessmentActivity struct {
-	Action string `json:"action"`
-	Date   int64  `json:"date"`
-	User   string `json:"user"`
+	Action string    `json:"action"`
+	Date   Timestamp `json:"date"`
+	User   string    `json:"user"`
 }
 
 // AssessmentData contains the results of an assessment
 type AssessmentData struct {
 	Contacts           []AssessmentContact  `json:"assessment_contacts"`
-	End                string               `json:"assessment_end"`
+	End                Timestamp            `json:"assessment_end"`
 	ReportLimitations  string               `json:"assessment_report_limitations"`
 	ReportOverview     string               `json:"assessment_report_overview"`
 	ProviderName       string               `json:"assessment_provider_name"`
@@ -53,19 +52,33 @@ type AssessmentData struct {
 	Environment        string               `json:"assessment_environment"`
 	Scope              string               `json:"assessment_scope"`
 	Status             string               `json:"assessment_status"`
-	Start              string               `json:"assessment_start"`
+	Start              Timestamp            `json:"assessment_start"`
 }
 
 // Assessment a conducted assessment of the project
 type Assessment struct {
+	RawJSON
+
 	ProjectID       string         `json:"project_id"`
 	Data            AssessmentData `json:"assessment_data"`
 	ParentProjectID string         `json:"parent_project_id"`
 	Name            string         `json:"assessment_name"`
 }
 
+// UnmarshalJSON decodes an assessment, keeping its JSON in RawJSON.
+func (a *Assessment) UnmarshalJSON(data []byte) error {
+	type assessment Assessment
+	return unmarshalModel(data, (*assessment)(a), &a.RawJSON)
+}
+
+// MarshalJSON encodes an assessment with the unmodelled fields in Extra.
+func (a Assessment) MarshalJSON() ([]byte, error) {
+	type assessment Assessment
+	return marshalModel(assessment(a), a.RawJSON)
+}
+
 // ListAssessments returns all assessments for a given project id
-func (s *ProjectsService) ListAssessments(ctx context.Context, projectID string) ([]*Assessment, *http.Response, error) {
+func (s *ProjectsService) ListAssessments(ctx context.Context, projectID string) ([]*Assessment, *Response, error) {
 	u := fmt.Sprintf("projects/%v/assessments", projectID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -73,7 +86,7 @@ func (s *ProjectsService) ListAssessments(ctx context.Context, projectID string)
 	}
 
 	var a []*Assessment
-	resp, err := s.client.Do(ctx, req, &a)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.ListAssessments", ProjectID: projectID, Request: req, Result: &a})
 	if err != nil {
 		return nil, resp, err
 	}
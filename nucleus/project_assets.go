@@ -2,11 +2,10 @@ package nucleus
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
-
-	"github.com/rsclarke/go-nucleus/nucleus/internal/util"
 )
 
 type DataSensitivity string
@@ -29,15 +28,17 @@ const (
 
 // Asset contains the property which describes an asset.
 type Asset struct {
+	RawJSON
+
 	OperatingSystemVersion string                 `json:"operating_system_version,omitempty"`
 	OperatingSystemName    string                 `json:"operating_system_name,omitempty"`
-	InactiveDate           string                 `json:"asset_inactive_date,omitempty"`
+	InactiveDate           Timestamp              `json:"asset_inactive_date"` // omitted when empty
 	DataSensitivityScore   DataSensitivity        `json:"asset_data_sensitivity_score,omitempty"`
 	ImageID                string                 `json:"image_id,omitempty"`
 	Users                  []string               `json:"asset_users,omitempty"`
 	Location               string                 `json:"asset_location,omitempty"`
 	Criticality            string                 `json:"asset_criticality,omitempty"`
-	CriticalityScore       string                 `json:"asset_criticality_score,omitempty"`
+	CriticalityScore       FlexFloat              `json:"asset_criticality_score"` // omitted when empty
 	Active                 bool                   `json:"active,omitempty"`
 	ImageDistro            string                 `json:"image_distro,omitempty"`
 	IPAddress              string                 `json:"ip_address,omitempty"`
@@ -46,7 +47,7 @@ type Asset struct {
 	Name                   string                 `json:"asset_name"`
 	MatchName              string                 `json:"asset_match_name,omitempty"`      // not in swagger
 	MatchNameLink          string                 `json:"asset_match_name_link,omitempty"` // not in swagger
-	Groups                 util.EmptyStrAsSlice   `json:"asset_groups,omitempty"`          // GetAsset returns the empty string "" for groups instead of the empty array
+	Groups                 FlexSlice              `json:"asset_groups,omitempty"`          // GetAsset returns the empty string "" for groups instead of the empty array
 	ImageRepo              string                 `json:"image_repo,omitempty"`
 	Info                   map[string]interface{} `json:"asset_info,omitempty"`
 	URL                    string                 `json:"url,omitempty"`
@@ -59,70 +60,121 @@ type Asset struct {
 	ImageTag               string                 `json:"image_tag,omitempty"`
 }
 
+// UnmarshalJSON decodes an asset, keeping its JSON in RawJSON.
+func (a *Asset) UnmarshalJSON(data []byte) error {
+	type asset Asset
+	return unmarshalModel(data, (*asset)(a), &a.RawJSON)
+}
+
+// MarshalJSON encodes an asset with the unmodelled fields in Extra.
+func (a Asset) MarshalJSON() ([]byte, error) {
+	type asset Asset
+	v := struct {
+		asset
+		InactiveDate     *Timestamp `json:"asset_inactive_date,omitempty"`
+		CriticalityScore *FlexFloat `json:"asset_criticality_score,omitempty"`
+	}{asset: asset(a)}
+	if !a.InactiveDate.isEmpty() {
+		v.InactiveDate = &a.InactiveDate
+	}
+	if !a.CriticalityScore.isEmpty() {
+		v.CriticalityScore = &a.CriticalityScore
+	}
+	return marshalModel(v, a.RawJSON)
+}
+
 // AssetVuln includes asset and vulnerability information (not as detailed as Asset)
 // There is a fair amount of duplication here which needs to be tidied up.
 type AssetVuln struct {
-	ID                        string             `json:"asset_id"`
-	Name                      string             `json:"asset_name"`
-	IPAddress                 string             `json:"ip_address"`
-	Groups                    []string           `json:"asset_groups"`
-	Type                      string             `json:"asset_type"`
-	ScanDate                  string             `json:"scan_date"`
-	Info                      util.EmptyStrAsMap `json:"asset_info"` // ListAssets returns the ass info as empty string instead of empty array
-	ScanDateTimestmap         int64              `json:"scan_date_timestamp"`
-	OperatingSystemName       string             `json:"operating_system_name"`
-	MACAddress                string             `json:"mac_address"`
-	FindingCountCritical      string             `json:"finding_count_critical"`
-	FindingCountHigh          string             `json:"finding_count_high"`
-	FindingCountMedium        string             `json:"finding_count_medium"`
-	FindingCountLow           string             `json:"finding_count_low"`
-	FindingCountInformational string             `json:"finding_count_informational"`
-	FindingCountPass          string             `json:"finding_count_pass"`
-	FindingCountFail          string             `json:"finding_count_fail"`
-	FindingVulnerabilityScore string             `json:"finding_vulnerability_score"`
-	Public                    string             `json:"asset_public"`
-	Criticality               string             `json:"asset_criticality"`
-	DataSensitivityScore      DataSensitivity    `json:"asset_data_sensitivity_score"`
-	ComplianceScore           Compliance         `json:"asset_complianced_score"`
-	CriticalityScore          string             `json:"asset_criticality_score"`
-	InactiveDate              string             `json:"asset_inactive_date"`
-	ImageID                   string             `json:"image_id"`
-	ImageDistro               string             `json:"image_distro"`
-	ImageRepo                 string             `json:"image_repo"`
-	ImageTag                  string             `json:"image_tag"`
-	Active                    bool               `json:"active"`
+	RawJSON
+
+	ID                        string          `json:"asset_id"`
+	Name                      string          `json:"asset_name"`
+	IPAddress                 string          `json:"ip_address"`
+	Groups                    []string        `json:"asset_groups"`
+	Type                      string          `json:"asset_type"`
+	ScanDate                  Timestamp       `json:"scan_date"`
+	Info                      FlexMap         `json:"asset_info"` // ListAssets returns the ass info as empty string instead of empty array
+	ScanDateTimestmap         Timestamp       `json:"scan_date_timestamp"`
+	OperatingSystemName       string          `json:"operating_system_name"`
+	MACAddress                string          `json:"mac_address"`
+	FindingCountCritical      FlexInt         `json:"finding_count_critical"`
+	FindingCountHigh          FlexInt         `json:"finding_count_high"`
+	FindingCountMedium        FlexInt         `json:"finding_count_medium"`
+	FindingCountLow           FlexInt         `json:"finding_count_low"`
+	FindingCountInformational FlexInt         `json:"finding_count_informational"`
+	FindingCountPass          FlexInt         `json:"finding_count_pass"`
+	FindingCountFail          FlexInt         `json:"finding_count_fail"`
+	FindingVulnerabilityScore FlexFloat       `json:"finding_vulnerability_score"`
+	Public                    string          `json:"asset_public"`
+	Criticality               string          `json:"asset_criticality"`
+	DataSensitivityScore      DataSensitivity `json:"asset_data_sensitivity_score"`
+	ComplianceScore           Compliance      `json:"asset_complianced_score"`
+	CriticalityScore          FlexFloat       `json:"asset_criticality_score"`
+	InactiveDate              Timestamp       `json:"asset_inactive_date"`
+	ImageID                   string          `json:"image_id"`
+	ImageDistro               string          `json:"image_distro"`
+	ImageRepo                 string          `json:"image_repo"`
+	ImageTag                  string          `json:"image_tag"`
+	Active                    bool            `json:"active"`
+}
+
+// UnmarshalJSON decodes an asset, keeping its JSON in RawJSON.
+func (a *AssetVuln) UnmarshalJSON(data []byte) error {
+	type assetVuln AssetVuln
+	return unmarshalModel(data, (*assetVuln)(a), &a.RawJSON)
+}
+
+// MarshalJSON encodes an asset with the unmodelled fields in Extra.
+func (a AssetVuln) MarshalJSON() ([]byte, error) {
+	type assetVuln AssetVuln
+	return marshalModel(assetVuln(a), a.RawJSON)
 }
 
 type FindingSummaryRecord struct {
-	AssetFixedCount      int64                `json:"asset_fixed_count"`
-	AssetMitigatedCount  int64                `json:"asset_mitigated_count"`
-	AssetCount           string               `json:"asset_count"`
-	Name                 string               `json:"finding_name"`
-	Number               string               `json:"finding_number"`
-	Discovered           string               `json:"finding_discovered"`
-	Exploitable          ExploitableFinding   `json:"finding_exploitable"`
-	Result               string               `json:"finding_result"`
-	Severity             string               `json:"finding_severity"`
-	Status               string               `json:"finding_status"`
-	CVE                  string               `json:"finding_cve"`
-	Count                string               `json:"finding_count"`
-	IAVA                 string               `json:"finding_iava"`
-	ScanDate             string               `json:"scan_date"`
-	ScanType             string               `json:"scan_type"`
-	IssueOpenCount       int64                `json:"issue_open_count"`
-	IssueClosedCount     int64                `json:"issue_closed_count"`
-	Issues               util.EmptyStrAsSlice `json:"issues"`
+	RawJSON
+
+	AssetFixedCount      int64              `json:"asset_fixed_count"`
+	AssetMitigatedCount  int64              `json:"asset_mitigated_count"`
+	AssetCount           FlexInt            `json:"asset_count"`
+	Name                 string             `json:"finding_name"`
+	Number               string             `json:"finding_number"`
+	Discovered           Timestamp          `json:"finding_discovered"`
+	Exploitable          ExploitableFinding `json:"finding_exploitable"`
+	Result               string             `json:"finding_result"`
+	Severity             string             `json:"finding_severity"`
+	Status               string             `json:"finding_status"`
+	CVE                  string             `json:"finding_cve"`
+	Count                FlexInt            `json:"finding_count"`
+	IAVA                 string             `json:"finding_iava"`
+	ScanDate             Timestamp          `json:"scan_date"`
+	ScanType             string             `json:"scan_type"`
+	IssueOpenCount       int64              `json:"issue_open_count"`
+	IssueClosedCount     int64              `json:"issue_closed_count"`
+	Issues               FlexSlice          `json:"issues"`
 	ComplianceFrameworks []struct {
 		Name string `json:"framework_name"`
 	} `json:"compliance_frameworks"`
 }
 
+// UnmarshalJSON decodes a finding, keeping its JSON in RawJSON.
+func (r *FindingSummaryRecord) UnmarshalJSON(data []byte) error {
+	type findingSummaryRecord FindingSummaryRecord
+	return unmarshalModel(data, (*findingSummaryRecord)(r), &r.RawJSON)
+}
+
+// MarshalJSON encodes a finding with the unmodelled fields in Extra.
+func (r FindingSummaryRecord) MarshalJSON() ([]byte, error) {
+	type findingSummaryRecord FindingSummaryRecord
+	return marshalModel(findingSummaryRecord(r), r.RawJSON)
+}
+
 type AssetGroup struct {
 	Name string `json:"asset_group"`
 }
 
 // GetAsset returns details on a specific project
-func (s *ProjectsService) GetAsset(ctx context.Context, projectID string, assetID string) (*Asset, *http.Response, error) {
+func (s *ProjectsService) GetAsset(ctx context.Context, projectID string, assetID string) (*Asset, *Response, error) {
 	u := fmt.Sprintf("projects/%v/assets/%v", projectID, assetID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -130,7 +182,7 @@ func (s *ProjectsService) GetAsset(ctx context.Context, projectID string, assetI
 	}
 
 	a := new(Asset)
-	resp, err := s.client.Do(ctx, req, a)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.GetAsset", ProjectID: projectID, AssetID: assetID, Request: req, Result: a})
 	if err != nil {
 		return nil, resp, err
 	}
@@ -148,11 +200,53 @@ type ListAssetsRequest struct {
 	InactiveAssets bool
 }
 
-func (s *ProjectsService) ListAssets(ctx context.Context, projectID string, request ListAssetsRequest) ([]*AssetVuln, *http.Response, error) {
+func (s *ProjectsService) ListAssets(ctx context.Context, projectID string, request ListAssetsRequest) ([]*AssetVuln, *Response, error) {
+	req, err := s.newListAssetsRequest(projectID, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var a []*AssetVuln
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.ListAssets", ProjectID: projectID, Request: req, Result: &a})
+	if err != nil {
+		return nil, resp, err
+	}
+	resp.populatePageValues(request.Start, request.Limit, len(a))
+
+	return a, resp, nil
+}
+
+// ListAssetsFunc calls fn for each asset in the project matching request as
+// it is decoded from the response, without holding the whole list in memory.
+// Returning an error from fn stops the listing and ListAssetsFunc returns it.
+func (s *ProjectsService) ListAssetsFunc(ctx context.Context, projectID string, request ListAssetsRequest, fn func(*AssetVuln) error) (*Response, error) {
+	req, err := s.newListAssetsRequest(projectID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	resp, err := s.client.callStream(ctx, &Call{Operation: "Projects.ListAssetsFunc", ProjectID: projectID, Request: req}, func(dec *json.Decoder) error {
+		a := new(AssetVuln)
+		if err := dec.Decode(a); err != nil {
+			return err
+		}
+		n++
+		return fn(a)
+	})
+	if err != nil {
+		return resp, err
+	}
+	resp.populatePageValues(request.Start, request.Limit, n)
+
+	return resp, nil
+}
+
+func (s *ProjectsService) newListAssetsRequest(projectID string, request ListAssetsRequest) (*http.Request, error) {
 	u := fmt.Sprintf("projects/%v/assets", projectID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	q := req.URL.Query()
@@ -177,16 +271,10 @@ func (s *ProjectsService) ListAssets(ctx context.Context, projectID string, requ
 
 	req.URL.RawQuery = q.Encode()
 
-	var a []*AssetVuln
-	resp, err := s.client.Do(ctx, req, &a)
-	if err != nil {
-		return nil, resp, err
-	}
-
-	return a, resp, nil
+	return req, nil
 }
 
-func (s *ProjectsService) ListAssetFindings(ctx context.Context, projectID string, assetID string) ([]*FindingSummaryRecord, *http.Response, error) {
+func (s *ProjectsService) ListAssetFindings(ctx context.Context, projectID string, assetID string) ([]*FindingSummaryRecord, *Response, error) {
 	u := fmt.Sprintf("projects/%v/assets/%v/findings", projectID, assetID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -194,7 +282,7 @@ func (s *ProjectsService) ListAssetFindings(ctx context.Context, projectID strin
 	}
 
 	var r []*FindingSummaryRecord
-	resp, err := s.client.Do(ctx, req, &r)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.ListAssetFindings", ProjectID: projectID, AssetID: assetID, Request: req, Result: &r})
 	if err != nil {
 		return nil, resp, err
 	}
@@ -202,7 +290,27 @@ func (s *ProjectsService) ListAssetFindings(ctx context.Context, projectID strin
 	return r, resp, nil
 }
 
-func (s *ProjectsService) ListAssetGroups(ctx context.Context, projectID string) ([]*AssetGroup, *http.Response, error) {
+// ListAssetFindingsFunc calls fn for each finding of the asset as it is
+// decoded from the response, without holding the whole list in memory.
+// Returning an error from fn stops the listing and ListAssetFindingsFunc
+// returns it.
+func (s *ProjectsService) ListAssetFindingsFunc(ctx context.Context, projectID string, assetID string, fn func(*FindingSummaryRecord) error) (*Response, error) {
+	u := fmt.Sprintf("projects/%v/assets/%v/findings", projectID, assetID)
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.callStream(ctx, &Call{Operation: "Projects.ListAssetFindingsFunc", ProjectID: projectID, AssetID: assetID, Request: req}, func(dec *json.Decoder) error {
+		r := new(FindingSummaryRecord)
+		if err := dec.Decode(r); err != nil {
+			return err
+		}
+		return fn(r)
+	})
+}
+
+func (s *ProjectsService) ListAssetGroups(ctx context.Context, projectID string) ([]*AssetGroup, *Response, error) {
 	u := fmt.Sprintf("projects/%v/assets/groups", projectID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -210,7 +318,7 @@ func (s *ProjectsService) ListAssetGroups(ctx context.Context, projectID string)
 	}
 
 	var g []*AssetGroup
-	resp, err := s.client.Do(ctx, req, &g)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.ListAssetGroups", ProjectID: projectID, Request: req, Result: &g})
 	if err != nil {
 		return nil, resp, err
 	}
@@ -224,7 +332,7 @@ type UpdateAssetResponse struct {
 	Success       bool     `json:"success"`
 }
 
-func (s *ProjectsService) UpdateAsset(ctx context.Context, projectID string, asset *Asset) (*UpdateAssetResponse, *http.Response, error) {
+func (s *ProjectsService) UpdateAsset(ctx context.Context, projectID string, asset *Asset) (*UpdateAssetResponse, *Response, error) {
 	assetID := asset.ID
 	trimAsset := *asset
 	trimAsset.ID = ""
@@ -236,7 +344,7 @@ func (s *ProjectsService) UpdateAsset(ctx context.Context, projectID string, ass
 	}
 
 	r := new(UpdateAssetResponse)
-	resp, err := s.client.Do(ctx, req, &r)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.UpdateAsset", ProjectID: projectID, AssetID: assetID, Request: req, Result: &r})
 	if err != nil {
 		return nil, resp, err
 	}
@@ -249,7 +357,7 @@ type CreateAssetResponse struct {
 	Success bool   `json:"success"`
 }
 
-func (s *ProjectsService) CreateAsset(ctx context.Context, projectID string, asset *Asset) (*CreateAssetResponse, *http.Response, error) {
+func (s *ProjectsService) CreateAsset(ctx context.Context, projectID string, asset *Asset) (*CreateAssetResponse, *Response, error) {
 	trimAsset := *asset
 	trimAsset.ID = ""
 
@@ -260,10 +368,63 @@ func (s *ProjectsService) CreateAsset(ctx context.Context, projectID string, ass
 	}
 
 	r := new(CreateAssetResponse)
-	resp, err := s.client.Do(ctx, req, &r)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.CreateAsset", ProjectID: projectID, Request: req, Result: &r})
 	if err != nil {
 		return nil, resp, err
 	}
 
 	return r, resp, nil
 }
+
+// AssetIterator walks every asset matched by a ListAssetsRequest, fetching
+// pages lazily as Next is called.
+type AssetIterator struct {
+	pager
+	page []*AssetVuln
+}
+
+// Next advances the iterator to the next asset, returning false when there
+// are no more assets or an error occurred.
+func (it *AssetIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current asset.
+func (it *AssetIterator) Value() *AssetVuln {
+	if it.pos < 0 || it.pos >= len(it.page) {
+		return nil
+	}
+	return it.page[it.pos]
+}
+
+// ListAssetsIterator returns an iterator over all assets in the project
+// matching request. request.Start is the offset of the first asset and
+// request.Limit the page size used for each call to ListAssets.
+func (s *ProjectsService) ListAssetsIterator(ctx context.Context, projectID string, request ListAssetsRequest) *AssetIterator {
+	it := new(AssetIterator)
+	it.pager = newPager(ctx, request.Start, request.Limit, func(ctx context.Context, start, limit int64) (int, error) {
+		r := request
+		r.Start, r.Limit = start, limit
+		a, _, err := s.ListAssets(ctx, projectID, r)
+		if err != nil {
+			return 0, err
+		}
+		it.page = a
+		return len(a), nil
+	})
+	return it
+}
+
+// ListAllAssets returns all assets in the project matching request, following
+// pages until the API has no more to return.
+func (s *ProjectsService) ListAllAssets(ctx context.Context, projectID string, request ListAssetsRequest) ([]*AssetVuln, error) {
+	var all []*AssetVuln
+	it := s.ListAssetsIterator(ctx, projectID, request)
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
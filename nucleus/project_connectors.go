@@ -3,10 +3,11 @@ package nucleus
 import (
 	"context"
 	"fmt"
-	"net/http"
 )
 
 type Connector struct {
+	RawJSON
+
 	ID          string                   `json:"connector_id"`
 	Type        string                   `json:"connector_type"`
 	Name        string                   `json:"connector_name"`
@@ -14,8 +15,20 @@ type Connector struct {
 	Fields      []map[string]interface{} `json:"connector_fields"`
 }
 
+// UnmarshalJSON decodes a connector, keeping its JSON in RawJSON.
+func (c *Connector) UnmarshalJSON(data []byte) error {
+	type connector Connector
+	return unmarshalModel(data, (*connector)(c), &c.RawJSON)
+}
+
+// MarshalJSON encodes a connector with the unmodelled fields in Extra.
+func (c Connector) MarshalJSON() ([]byte, error) {
+	type connector Connector
+	return marshalModel(connector(c), c.RawJSON)
+}
+
 // ListProjects returns a list of all projects with the current status
-func (s *ProjectsService) ListConnectors(ctx context.Context, projectID string) ([]*Connector, *http.Response, error) {
+func (s *ProjectsService) ListConnectors(ctx context.Context, projectID string) ([]*Connector, *Response, error) {
 	u := fmt.Sprintf("projects/%v/connectors", projectID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -23,7 +36,7 @@ func (s *ProjectsService) ListConnectors(ctx context.Context, projectID string)
 	}
 
 	var c []*Connector
-	resp, err := s.client.Do(ctx, req, &c)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.ListConnectors", ProjectID: projectID, Request: req, Result: &c})
 	if err != nil {
 		return nil, resp, err
 	}
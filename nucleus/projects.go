@@ -3,7 +3,6 @@ package nucleus
 import (
 	"context"
 	"fmt"
-	"net/http"
 )
 
 // ProjectsService provides access to project related functions
@@ -11,6 +10,8 @@ type ProjectsService service
 
 // Project holds the metadata
 type Project struct {
+	RawJSON
+
 	TrackingMethod string   `json:"tracking_method"`
 	Name           string   `json:"project_name"`
 	Description    string   `json:"project_description"`
@@ -19,15 +20,27 @@ type Project struct {
 	Org            string   `json:"project_org"`
 }
 
+// UnmarshalJSON decodes a project, keeping its JSON in RawJSON.
+func (p *Project) UnmarshalJSON(data []byte) error {
+	type project Project
+	return unmarshalModel(data, (*project)(p), &p.RawJSON)
+}
+
+// MarshalJSON encodes a project with the unmodelled fields in Extra.
+func (p Project) MarshalJSON() ([]byte, error) {
+	type project Project
+	return marshalModel(project(p), p.RawJSON)
+}
+
 // ListProjects returns a list of all projects with the current status
-func (s *ProjectsService) ListProjects(ctx context.Context) ([]*Project, *http.Response, error) {
+func (s *ProjectsService) ListProjects(ctx context.Context) ([]*Project, *Response, error) {
 	req, err := s.client.NewRequest("GET", "projects", nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var p []*Project
-	resp, err := s.client.Do(ctx, req, &p)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.ListProjects", Request: req, Result: &p})
 	if err != nil {
 		return nil, resp, err
 	}
@@ -36,7 +49,7 @@ func (s *ProjectsService) ListProjects(ctx context.Context) ([]*Project, *http.R
 }
 
 // GetProject returns details on a specific project
-func (s *ProjectsService) GetProject(ctx context.Context, projectID string) (*Project, *http.Response, error) {
+func (s *ProjectsService) GetProject(ctx context.Context, projectID string) (*Project, *Response, error) {
 	u := fmt.Sprintf("projects/%v", projectID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -44,7 +57,7 @@ func (s *ProjectsService) GetProject(ctx context.Context, projectID string) (*Pr
 	}
 
 	p := new(Project)
-	resp, err := s.client.Do(ctx, req, p)
+	resp, err := s.client.call(ctx, &Call{Operation: "Projects.GetProject", ProjectID: projectID, Request: req, Result: p})
 	if err != nil {
 		return nil, resp, err
 	}
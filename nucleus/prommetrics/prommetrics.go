@@ -0,0 +1,194 @@
+// Package prommetrics implements nucleus.Metrics by exposing the
+// measurements in the Prometheus text exposition format, without depending on
+// the Prometheus client libraries.
+//
+//	m := prommetrics.New()
+//	client, err := nucleus.NewClientWithOptions(org, nucleus.WithMetrics(m))
+//	http.Handle("/metrics", m)
+package prommetrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the request duration
+// histogram buckets.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type requestKey struct {
+	operation string
+	code      int
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// Metrics collects the measurements of a nucleus.Client and serves them to
+// Prometheus. It is safe for concurrent use.
+type Metrics struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	duration map[string]*histogram
+	retries  map[string]uint64
+	bytes    map[string]uint64
+}
+
+// New returns a Metrics using DefaultBuckets.
+func New() *Metrics {
+	return NewWithBuckets(DefaultBuckets)
+}
+
+// NewWithBuckets returns a Metrics whose request duration histogram uses the
+// given bucket upper bounds, in seconds.
+func NewWithBuckets(buckets []float64) *Metrics {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &Metrics{
+		buckets:  b,
+		requests: map[requestKey]uint64{},
+		duration: map[string]*histogram{},
+		retries:  map[string]uint64{},
+		bytes:    map[string]uint64{},
+	}
+}
+
+// RequestCompleted implements nucleus.Metrics.
+func (m *Metrics) RequestCompleted(operation string, statusCode int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{operation, statusCode}]++
+
+	h := m.duration[operation]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.duration[operation] = h
+	}
+	secs := latency.Seconds()
+	for i, le := range m.buckets {
+		if secs <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+// RequestRetried implements nucleus.Metrics.
+func (m *Metrics) RequestRetried(operation string, attempt int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retries[operation]++
+}
+
+// BytesReceived implements nucleus.Metrics.
+func (m *Metrics) BytesReceived(operation string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bytes[operation] += uint64(n)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_ = m.Expose(w)
+}
+
+// Expose writes the metrics to w in the Prometheus text exposition format.
+func (m *Metrics) Expose(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	header(bw, "nucleus_client_requests_total", "counter", "Number of Nucleus API calls by operation and HTTP status code.")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation < keys[j].operation
+		}
+		return keys[i].code < keys[j].code
+	})
+	for _, k := range keys {
+		fmt.Fprintf(bw, "nucleus_client_requests_total{operation=%s,code=\"%d\"} %d\n", quote(k.operation), k.code, m.requests[k])
+	}
+
+	header(bw, "nucleus_client_request_duration_seconds", "histogram", "Duration of Nucleus API calls, including retries.")
+	for _, op := range sortedKeys(m.duration) {
+		h := m.duration[op]
+		var cumulative uint64
+		for i, le := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(bw, "nucleus_client_request_duration_seconds_bucket{operation=%s,le=\"%s\"} %d\n", quote(op), formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(bw, "nucleus_client_request_duration_seconds_bucket{operation=%s,le=\"+Inf\"} %d\n", quote(op), h.count)
+		fmt.Fprintf(bw, "nucleus_client_request_duration_seconds_sum{operation=%s} %s\n", quote(op), formatFloat(h.sum))
+		fmt.Fprintf(bw, "nucleus_client_request_duration_seconds_count{operation=%s} %d\n", quote(op), h.count)
+	}
+
+	header(bw, "nucleus_client_retries_total", "counter", "Number of retried Nucleus API requests by operation.")
+	for _, op := range sortedKeys(m.retries) {
+		fmt.Fprintf(bw, "nucleus_client_retries_total{operation=%s} %d\n", quote(op), m.retries[op])
+	}
+
+	header(bw, "nucleus_client_response_bytes_total", "counter", "Number of response body bytes received by operation.")
+	for _, op := range sortedKeys(m.bytes) {
+		fmt.Fprintf(bw, "nucleus_client_response_bytes_total{operation=%s} %d\n", quote(op), m.bytes[op])
+	}
+
+	return bw.Flush()
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sortedKeys returns the keys of m, which must be a map with string keys, in
+// order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*histogram:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]uint64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// quote returns v as a quoted label value.
+func quote(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
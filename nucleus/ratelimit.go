@@ -0,0 +1,137 @@
+package nucleus
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// adaptiveRecovery is the fraction of the configured rate restored after
+// each successful response once an adaptive RateLimiter has backed off.
+const adaptiveRecovery = 0.05
+
+// defaultAdaptiveFloor is the fraction of the configured rate an adaptive
+// RateLimiter backs off to when no minimum rate is given.
+const defaultAdaptiveFloor = 0.1
+
+// RateLimiter is a token bucket limiting the rate at which a Client sends
+// requests. It is safe for concurrent use and may be shared between clients.
+type RateLimiter struct {
+	mu sync.Mutex
+
+	limit  float64 // configured tokens per second
+	rate   float64 // current tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	// minRate is the floor an adaptive limiter backs off to; zero when the
+	// limiter is not adaptive.
+	minRate     float64
+	pausedUntil time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing rps requests per second on
+// average with bursts of up to burst requests. A non-positive rps disables
+// limiting.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		limit:  rps,
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// NewAdaptiveRateLimiter returns a RateLimiter like NewRateLimiter which also
+// halves its rate, down to minRPS, every time the API responds with 429 Too
+// Many Requests, then gradually recovers as requests succeed. A non-positive
+// minRPS backs off down to a tenth of rps, and one above rps is lowered to
+// rps, which does not adapt.
+func NewAdaptiveRateLimiter(rps float64, burst int, minRPS float64) *RateLimiter {
+	l := NewRateLimiter(rps, burst)
+	switch {
+	case minRPS <= 0:
+		minRPS = rps * defaultAdaptiveFloor
+	case minRPS > rps:
+		minRPS = rps
+	}
+	l.minRate = minRPS
+	return l
+}
+
+// Rate returns the current number of requests allowed per second.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.limit <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(now)
+	l.tokens--
+
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if d := l.pausedUntil.Sub(now); d > wait {
+		wait = d
+	}
+	l.mu.Unlock()
+
+	if err := sleep(ctx, wait); err != nil {
+		// Return the unused token so cancelled callers don't slow the others.
+		l.mu.Lock()
+		l.tokens = math.Min(l.tokens+1, l.burst)
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// refill adds the tokens accumulated since the last call. l.mu must be held.
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last).Seconds()
+	if elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed*l.rate)
+		l.last = now
+	}
+}
+
+// observe adapts the rate of an adaptive limiter to the response received.
+func (l *RateLimiter) observe(resp *http.Response) {
+	if l == nil || l.minRate == 0 || resp == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		l.rate = math.Max(l.rate/2, l.minRate)
+		if d, ok := parseRetryAfter(resp); ok {
+			l.pausedUntil = now.Add(d)
+		}
+		return
+	}
+	if resp.StatusCode < 400 && l.rate < l.limit {
+		l.rate = math.Min(l.rate+l.limit*adaptiveRecovery, l.limit)
+	}
+}
@@ -0,0 +1,89 @@
+package nucleus
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// RawJSON is embedded in the models decoded from API responses to keep the
+// JSON they were decoded from, including the fields they do not model.
+//
+// The fields in Extra are encoded along with the modelled ones, so that a
+// model read from the API, modified and sent back, as when updating an
+// asset, keeps the fields the client does not know about.
+type RawJSON struct {
+	// Raw is the JSON object the model was decoded from.
+	Raw json.RawMessage `json:"-"`
+
+	// Extra holds the fields of Raw that the model does not have, keyed by
+	// name.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (r *RawJSON) rawJSON() *RawJSON {
+	return r
+}
+
+// rawModel is implemented by the models embedding RawJSON.
+type rawModel interface {
+	rawJSON() *RawJSON
+}
+
+var rawModelType = reflect.TypeOf((*rawModel)(nil)).Elem()
+
+// unmarshalModel decodes data into v, a pointer to a model type without
+// methods, and keeps data and its unmodelled fields in r. As when decoding
+// into an existing value with encoding/json, the fields of Extra absent from
+// data are kept.
+func unmarshalModel(data []byte, v interface{}, r *RawJSON) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	modelled := structFields(reflect.TypeOf(v).Elem())
+	extra := make(map[string]json.RawMessage, len(r.Extra))
+	for name, value := range r.Extra {
+		extra[name] = value
+	}
+	for name, value := range fields {
+		if _, ok := modelled.lookup(name); !ok {
+			extra[name] = value
+		}
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+
+	// The decoder may reuse data once we return.
+	r.Raw = append(json.RawMessage(nil), data...)
+	r.Extra = extra
+	return nil
+}
+
+// marshalModel encodes v, a model type without methods, adding the fields
+// of r.Extra it does not have.
+func marshalModel(v interface{}, r RawJSON) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(r.Extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range r.Extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
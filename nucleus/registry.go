@@ -0,0 +1,142 @@
+package nucleus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry holds a Client per organisation, each with its own credentials,
+// rate limits and other options, and runs operations across all of them.
+// It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{clients: map[string]*Client{}}
+}
+
+// Register creates a Client for organisation with NewClientWithOptions and
+// adds it to the registry, replacing any previous one.
+func (r *Registry) Register(organisation string, opts ...ClientOption) (*Client, error) {
+	c, err := NewClientWithOptions(organisation, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("organisation %v: %w", organisation, err)
+	}
+	r.Add(organisation, c)
+	return c, nil
+}
+
+// Add adds c to the registry as the Client for organisation, replacing any
+// previous one.
+func (r *Registry) Add(organisation string, c *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[organisation] = c
+}
+
+// Remove removes the Client for organisation.
+func (r *Registry) Remove(organisation string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.clients, organisation)
+}
+
+// Client returns the Client for organisation.
+func (r *Registry) Client(organisation string) (*Client, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.clients[organisation]
+	return c, ok
+}
+
+// Organisations returns the sorted names of the registered organisations.
+func (r *Registry) Organisations() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orgs := make([]string, 0, len(r.clients))
+	for org := range r.clients {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
+// OrgFunc is an operation run for one organisation by Registry.ForEach.
+type OrgFunc func(ctx context.Context, organisation string, c *Client) (interface{}, error)
+
+// OrgResult is the outcome of an OrgFunc for one organisation.
+type OrgResult struct {
+	Organisation string
+	Value        interface{}
+	Err          error
+}
+
+// OrgResults are the outcomes of Registry.ForEach, sorted by organisation.
+type OrgResults []OrgResult
+
+// Errors returns the errors of the organisations that failed, keyed by
+// organisation, or nil if none did.
+func (rs OrgResults) Errors() map[string]error {
+	var errs map[string]error
+	for _, r := range rs {
+		if r.Err != nil {
+			if errs == nil {
+				errs = map[string]error{}
+			}
+			errs[r.Organisation] = r.Err
+		}
+	}
+	return errs
+}
+
+// ForEach runs fn for every registered organisation concurrently, with at
+// most parallelism calls running at once, or all of them if parallelism is
+// not positive. It waits for every call to finish and returns their results;
+// a failure for one organisation does not stop the others. Organisations not
+// yet started when ctx is done get ctx.Err() as their error.
+func (r *Registry) ForEach(ctx context.Context, parallelism int, fn OrgFunc) OrgResults {
+	orgs := r.Organisations()
+	if parallelism <= 0 {
+		parallelism = len(orgs)
+	}
+
+	batch, _ := RunBatch(ctx, len(orgs), BatchOptions{Parallelism: parallelism, Ordered: true}, func(ctx context.Context, i int) (interface{}, error) {
+		c, ok := r.Client(orgs[i])
+		if !ok {
+			return nil, fmt.Errorf("organisation %v was removed", orgs[i])
+		}
+		return fn(ctx, orgs[i], c)
+	})
+
+	results := make(OrgResults, len(batch))
+	for i, b := range batch {
+		results[i] = OrgResult{Organisation: orgs[b.Index], Value: b.Value, Err: b.Err}
+	}
+	return results
+}
+
+// ListProjects lists the projects of every registered organisation
+// concurrently. The projects and errors are keyed by organisation.
+func (r *Registry) ListProjects(ctx context.Context) (map[string][]*Project, map[string]error) {
+	results := r.ForEach(ctx, 0, func(ctx context.Context, _ string, c *Client) (interface{}, error) {
+		p, _, err := c.Projects.ListProjects(ctx)
+		return p, err
+	})
+
+	projects := make(map[string][]*Project, len(results))
+	for _, res := range results {
+		if res.Err == nil {
+			projects[res.Organisation] = res.Value.([]*Project)
+		}
+	}
+	return projects, results.Errors()
+}
@@ -0,0 +1,100 @@
+package nucleus
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+)
+
+// requestIDHeaders are the headers checked, in order, for the identifier
+// assigned to a request by the API or a proxy in front of it.
+var requestIDHeaders = []string{"X-Request-Id", "X-Amzn-Requestid", "X-Correlation-Id"}
+
+// Rate represents the rate limit reported by the API for the current client.
+type Rate struct {
+	// Limit is the number of requests allowed in the current window.
+	Limit int
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+	// Reset is when the current window ends.
+	Reset time.Time
+}
+
+// Response wraps the http.Response returned by the API and provides
+// convenient access to metadata such as rate limits and pagination.
+type Response struct {
+	*http.Response
+
+	// Rate is the rate limit parsed from the response headers. Its fields
+	// are zero when the API did not send them.
+	Rate Rate
+
+	// RequestID identifies the request in the API or proxy logs, if given.
+	RequestID string
+
+	// Elapsed is the time taken by the call, including any retries.
+	Elapsed time.Duration
+
+	// Cached reports whether the response was served by the Cache of the
+	// Client, either stored or shared with a concurrent identical request,
+	// rather than received in reply to this call.
+	Cached bool
+
+	// NextStart is the start offset of the following page and HasMore
+	// reports whether it may hold results. They are only set by paginated
+	// methods, based on the page size requested and the number of items
+	// returned.
+	NextStart int64
+	HasMore   bool
+}
+
+// newResponse creates a new Response for the provided http.Response.
+// r must not be nil.
+func newResponse(r *http.Response) *Response {
+	response := &Response{Response: r}
+	response.Rate = parseRate(r)
+	for _, h := range requestIDHeaders {
+		if id := r.Header.Get(h); id != "" {
+			response.RequestID = id
+			break
+		}
+	}
+	return response
+}
+
+// populatePageValues sets NextStart and HasMore for a page of n items that
+// was requested starting at start with at most limit items.
+func (r *Response) populatePageValues(start, limit int64, n int) {
+	r.NextStart = start + int64(n)
+	r.HasMore = limit > 0 && int64(n) >= limit
+}
+
+// parseRate parses the rate limit headers of r.
+func parseRate(r *http.Response) Rate {
+	var rate Rate
+	if v := r.Header.Get(headerRateLimit); v != "" {
+		rate.Limit, _ = strconv.Atoi(strings.TrimSpace(v))
+	}
+	if v := r.Header.Get(headerRateRemaining); v != "" {
+		rate.Remaining, _ = strconv.Atoi(strings.TrimSpace(v))
+	}
+	if v := r.Header.Get(headerRateReset); v != "" {
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			// Small values are a number of seconds until the reset rather
+			// than a Unix timestamp.
+			if n < 1e9 {
+				rate.Reset = time.Now().Add(time.Duration(n) * time.Second)
+			} else {
+				rate.Reset = time.Unix(n, 0)
+			}
+		}
+	}
+	return rate
+}
@@ -0,0 +1,216 @@
+package nucleus
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckRetryFunc decides whether a request should be retried given the
+// response or error of the last attempt. Returning a non-nil error stops
+// retrying and that error is returned to the caller.
+type CheckRetryFunc func(ctx context.Context, req *http.Request, resp *http.Response, err error) (bool, error)
+
+// RetryPolicy configures how Client.Do retries failed requests.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values less than 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// attempts, 500ms and 30s if not positive. A Retry-After header asking
+	// for a longer delay than MaxBackoff stops retrying, and the response is
+	// returned as is, as a *RateLimitError for 429 responses.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction, between 0 and 1, of each backoff that is
+	// randomised to spread out retries from concurrent callers.
+	Jitter float64
+
+	// RetryableStatusCodes lists the HTTP status codes that are retried.
+	RetryableStatusCodes []int
+
+	// RetryableMethods lists the HTTP methods that may be retried. Requests
+	// with a body are only retried when it can be replayed, as is the case
+	// for requests created with NewRequest.
+	RetryableMethods []string
+
+	// CheckRetry, if set, replaces the default decision based on
+	// RetryableStatusCodes and RetryableMethods. Requests failing with a
+	// *CredentialsError are never retried.
+	CheckRetry CheckRetryFunc
+}
+
+// Defaults applied to the zero fields of a RetryPolicy.
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// DefaultRetryPolicy returns a RetryPolicy retrying idempotent requests on
+// network errors, 429 and 5xx gateway responses up to three more times.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  defaultMinBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		Jitter:      0.5,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryableMethods: []string{
+			http.MethodGet,
+			http.MethodHead,
+			http.MethodOptions,
+			http.MethodPut,
+			http.MethodDelete,
+		},
+	}
+}
+
+// shouldRetry reports whether another attempt should be made after attempt
+// number attempt (starting at 1) ended with resp or err.
+func (p *RetryPolicy) shouldRetry(ctx context.Context, req *http.Request, resp *http.Response, err error, attempt int) (bool, error) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false, nil
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false, nil
+	}
+	if isCredentialsError(err) {
+		return false, nil
+	}
+	if p.CheckRetry != nil {
+		return p.CheckRetry(ctx, req, resp, err)
+	}
+	return p.defaultCheckRetry(req, resp, err), nil
+}
+
+func (p *RetryPolicy) defaultCheckRetry(req *http.Request, resp *http.Response, err error) bool {
+	if !containsFold(p.RetryableMethods, req.Method) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait before the attempt following attempt,
+// preferring the server's Retry-After header when present. It returns false
+// if the server asked for a longer delay than MaxBackoff.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	maxBackoff := p.maxBackoff()
+	if d, ok := parseRetryAfter(resp); ok {
+		return d, d <= maxBackoff
+	}
+
+	d := float64(p.minBackoff()) * math.Pow(2, float64(attempt-1))
+	if d > float64(maxBackoff) {
+		d = float64(maxBackoff)
+	}
+	if p.Jitter > 0 {
+		j := math.Min(p.Jitter, 1)
+		d -= d * j * rand.Float64()
+	}
+	return time.Duration(d), true
+}
+
+func (p *RetryPolicy) minBackoff() time.Duration {
+	if p.MinBackoff > 0 {
+		return p.MinBackoff
+	}
+	return defaultMinBackoff
+}
+
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return defaultMaxBackoff
+}
+
+// parseRetryAfter returns the delay requested by the Retry-After header of
+// resp, which may be given in seconds or as an HTTP date.
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// drainBody discards what remains of a response body that will not be
+// decoded so that the underlying connection can be reused.
+func drainBody(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+}
+
+// rewindBody resets the body of req so that it can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
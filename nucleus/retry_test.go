@@ -0,0 +1,195 @@
+package nucleus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+	"github.com/rsclarke/go-nucleus/nucleus/nucleustest"
+)
+
+// countingTransport counts the requests sent through the client of a
+// nucleustest.Server.
+type countingTransport struct {
+	n         int64
+	transport http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&t.n, 1)
+	return t.transport.RoundTrip(req)
+}
+
+func (t *countingTransport) count() int {
+	return int(atomic.LoadInt64(&t.n))
+}
+
+// countingClient returns a client of srv counting its requests.
+func countingClient(srv *nucleustest.Server, opts ...nucleus.ClientOption) (*nucleus.Client, *countingTransport) {
+	counter := &countingTransport{transport: srv.Server.Client().Transport}
+	opts = append([]nucleus.ClientOption{nucleus.WithHTTPClient(&http.Client{Transport: counter})}, opts...)
+	return srv.Client(opts...), counter
+}
+
+func fastRetries(maxAttempts int) *nucleus.RetryPolicy {
+	p := nucleus.DefaultRetryPolicy()
+	p.MaxAttempts = maxAttempts
+	p.MinBackoff = time.Millisecond
+	p.MaxBackoff = 10 * time.Millisecond
+	return p
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		fault        nucleustest.Fault
+		policy       *nucleus.RetryPolicy
+		create       bool
+		wantRequests int
+		wantErr      func(error) bool
+	}{
+		{
+			name:         "recovers from 503",
+			fault:        nucleustest.ServerError(2, http.StatusServiceUnavailable),
+			policy:       fastRetries(4),
+			wantRequests: 3,
+		},
+		{
+			name:         "gives up after MaxAttempts",
+			fault:        nucleustest.ServerError(5, http.StatusBadGateway),
+			policy:       fastRetries(3),
+			wantRequests: 3,
+			wantErr:      nucleus.IsServerError,
+		},
+		{
+			name:         "does not retry 500",
+			fault:        nucleustest.ServerError(1, http.StatusInternalServerError),
+			policy:       fastRetries(4),
+			wantRequests: 1,
+			wantErr:      nucleus.IsServerError,
+		},
+		{
+			name:         "does not retry POST",
+			fault:        nucleustest.ServerError(1, http.StatusServiceUnavailable),
+			policy:       fastRetries(4),
+			create:       true,
+			wantRequests: 1,
+			wantErr:      nucleus.IsServerError,
+		},
+		{
+			name:         "no policy",
+			fault:        nucleustest.ServerError(1, http.StatusServiceUnavailable),
+			wantRequests: 1,
+			wantErr:      nucleus.IsServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := nucleustest.NewServer()
+			defer srv.Close()
+			projectID := srv.Store.AddProject(nucleus.Project{Name: "p"})
+			srv.InjectFault(tt.fault)
+
+			client, counter := countingClient(srv, nucleus.WithRetryPolicy(tt.policy))
+			var err error
+			if tt.create {
+				_, _, err = client.Projects.CreateAsset(context.Background(), projectID, &nucleus.Asset{Name: "a"})
+			} else {
+				_, _, err = client.Projects.ListProjects(context.Background())
+			}
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("error = %v, want nil", err)
+			case tt.wantErr != nil && !tt.wantErr(err):
+				t.Errorf("error = %v, not of the expected kind", err)
+			}
+			if got := counter.count(); got != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name         string
+		retryAfter   time.Duration
+		maxBackoff   time.Duration
+		wantRequests int
+		wantMinWait  time.Duration
+		wantErr      bool
+	}{
+		{
+			name:         "honoured",
+			retryAfter:   time.Second,
+			maxBackoff:   2 * time.Second,
+			wantRequests: 2,
+			wantMinWait:  900 * time.Millisecond,
+		},
+		{
+			name:         "longer than MaxBackoff",
+			retryAfter:   time.Hour,
+			maxBackoff:   100 * time.Millisecond,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := nucleustest.NewServer()
+			defer srv.Close()
+			srv.InjectFault(nucleustest.RateLimited(1, tt.retryAfter))
+
+			policy := fastRetries(3)
+			policy.MaxBackoff = tt.maxBackoff
+			client, counter := countingClient(srv, nucleus.WithRetryPolicy(policy))
+
+			start := time.Now()
+			_, _, err := client.Projects.ListProjects(context.Background())
+			elapsed := time.Since(start)
+
+			if tt.wantErr {
+				var rateErr *nucleus.RateLimitError
+				if !errors.As(err, &rateErr) || rateErr.RetryAfter != tt.retryAfter {
+					t.Errorf("error = %v, want a *RateLimitError with RetryAfter %v", err, tt.retryAfter)
+				}
+			} else if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if got := counter.count(); got != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", got, tt.wantRequests)
+			}
+			if elapsed < tt.wantMinWait || elapsed > tt.maxBackoff+time.Second {
+				t.Errorf("call took %v, want between %v and %v", elapsed, tt.wantMinWait, tt.maxBackoff+time.Second)
+			}
+		})
+	}
+}
+
+func TestRetryDefaultBackoff(t *testing.T) {
+	srv := nucleustest.NewServer()
+	defer srv.Close()
+	srv.InjectFault(nucleustest.ServerError(1, http.StatusServiceUnavailable))
+
+	client, counter := countingClient(srv, nucleus.WithRetryPolicy(&nucleus.RetryPolicy{
+		MaxAttempts:          2,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+		RetryableMethods:     []string{http.MethodGet},
+	}))
+	start := time.Now()
+	if _, _, err := client.Projects.ListProjects(context.Background()); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	// Without jitter the first backoff is the default MinBackoff, 500ms.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("call took %v, want at least the default backoff", elapsed)
+	}
+	if got := counter.count(); got != 2 {
+		t.Errorf("sent %d requests, want 2", got)
+	}
+}
@@ -0,0 +1,99 @@
+package nucleus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// DecodeFunc decodes a single element of a JSON array from dec.
+type DecodeFunc func(dec *json.Decoder) error
+
+// DoStream sends an API request whose response is a JSON array and calls fn
+// once for each element, with dec positioned at the start of the element, so
+// that arbitrarily large responses can be processed in constant memory. fn
+// must consume exactly one value from dec, typically by calling dec.Decode.
+// If fn returns an error, the rest of the response is discarded and that
+// error is returned.
+func (c *Client) DoStream(ctx context.Context, req *http.Request, fn DecodeFunc) (*Response, error) {
+	return c.callStream(ctx, &Call{Request: req}, fn)
+}
+
+// stream is the innermost Handler of DoStream.
+func (c *Client) stream(ctx context.Context, call *Call, fn DecodeFunc) (*Response, error) {
+	req := call.Request.WithContext(ctx)
+
+	start := time.Now()
+	resp, err := c.send(ctx, call, req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	response := newResponse(resp)
+	defer func() { response.Elapsed = time.Since(start) }()
+
+	if c := resp.StatusCode; c < 200 || c > 299 {
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		if err != nil {
+			return response, err
+		}
+		return response, checkResponse(resp, data)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if first, err := peekNonSpace(body); err != nil || first != '[' {
+		// Not an array: either an empty body, null, or an error envelope.
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return response, err
+		}
+		if err := checkResponse(resp, data); err != nil {
+			return response, err
+		}
+		if d := bytes.TrimSpace(data); len(d) == 0 || string(d) == "null" {
+			return response, nil
+		}
+		return response, fmt.Errorf("expected a JSON array in response to %v %v", req.Method, req.URL)
+	}
+
+	dec := json.NewDecoder(body)
+	if _, err := dec.Token(); err != nil {
+		return response, err
+	}
+	for dec.More() {
+		if err := fn(dec); err != nil {
+			return response, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// peekNonSpace skips leading JSON whitespace in r and returns the next byte
+// without consuming it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := r.ReadByte(); err != nil {
+				return 0, err
+			}
+		default:
+			return b[0], nil
+		}
+	}
+}
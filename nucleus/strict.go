@@ -0,0 +1,437 @@
+package nucleus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StrictDecoding reports differences between the responses of the API and
+// the models they are decoded into, which otherwise go unnoticed: fields the
+// models do not know about, and values of a type the models do not expect.
+//
+// Streamed calls, such as ListAssetsFunc, are not checked.
+type StrictDecoding struct {
+	// OnDrift, if set, is called with the drift found in each response.
+	OnDrift func(drift *SchemaDrift)
+
+	// Fail makes calls whose response drifted return the *SchemaDrift as
+	// their error, after decoding the response as usual. Otherwise the
+	// values of an unexpected type are left out of the decoded models, as if
+	// they were null, and the rest of the response is decoded.
+	Fail bool
+}
+
+// SchemaDrift reports the differences found between a response and the model
+// it was decoded into.
+type SchemaDrift struct {
+	// Operation is the operation of the call, as in Call.Operation.
+	Operation string
+
+	// Method and URL are those of the request.
+	Method string
+	URL    string
+
+	// Issues are the differences found, ordered by path. Differences found
+	// at the same path in several elements of an array are reported once.
+	Issues []DriftIssue
+}
+
+// DriftIssue is a difference between a response and its model.
+type DriftIssue struct {
+	// Path locates the value in the response, for example
+	// "$[3].asset_groups". It is that of the first occurrence when Count is
+	// more than one.
+	Path string
+
+	// Expected is the JSON type expected by the model, or empty for a field
+	// the model does not have.
+	Expected string
+
+	// Actual is the JSON type received.
+	Actual string
+
+	// Count is the number of times the difference occurred.
+	Count int
+}
+
+func (i DriftIssue) String() string {
+	if i.Expected == "" {
+		return fmt.Sprintf("unknown field %v (%v)", i.Path, i.Actual)
+	}
+	return fmt.Sprintf("%v: expected %v, got %v", i.Path, i.Expected, i.Actual)
+}
+
+func (d *SchemaDrift) Error() string {
+	issues := make([]string, len(d.Issues))
+	for i, issue := range d.Issues {
+		issues[i] = issue.String()
+	}
+	op := d.Operation
+	if op == "" {
+		op = d.Method + " " + d.URL
+	}
+	return fmt.Sprintf("nucleus: schema drift in %v response: %v", op, strings.Join(issues, "; "))
+}
+
+// decode decodes data, the body of the response to call, into v, its model,
+// after comparing them and reporting the drift found.
+func (s *StrictDecoding) decode(call *Call, data []byte, v interface{}) error {
+	w := &driftWalker{issues: map[string]*DriftIssue{}, mismatches: map[string]bool{}}
+	w.walk("$", "$", data, reflect.TypeOf(v))
+	if len(w.issues) == 0 {
+		return json.Unmarshal(data, v)
+	}
+
+	drift := &SchemaDrift{
+		Operation: call.Operation,
+		Method:    call.Request.Method,
+		URL:       call.Request.URL.String(),
+	}
+	for _, issue := range w.issues {
+		drift.Issues = append(drift.Issues, *issue)
+	}
+	sort.Slice(drift.Issues, func(i, j int) bool { return drift.Issues[i].Path < drift.Issues[j].Path })
+
+	if s.OnDrift != nil {
+		s.OnDrift(drift)
+	}
+	if s.Fail {
+		if err := json.Unmarshal(data, v); err != nil {
+			return err
+		}
+		return drift
+	}
+	if len(w.mismatches) > 0 {
+		data, _ = nullValues("$", data, w.mismatches)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// JSON types, as reported in a DriftIssue.
+const (
+	jsonNull    = "null"
+	jsonBool    = "boolean"
+	jsonNumber  = "number"
+	jsonString  = "string"
+	jsonArray   = "array"
+	jsonObject  = "object"
+	jsonInvalid = "invalid"
+)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// driftWalker walks a JSON value along with the Go type it is decoded into.
+type driftWalker struct {
+	// issues are keyed by the path of their first occurrence with the array
+	// indices removed, and their types.
+	issues map[string]*DriftIssue
+
+	// mismatches are the paths of every value of an unexpected type.
+	mismatches map[string]bool
+}
+
+// walk checks data, found at path, against t. key is path with the array
+// indices replaced by [*].
+func (w *driftWalker) walk(path, key string, data []byte, t reflect.Type) {
+	actual := jsonType(data)
+	if actual == jsonNull {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Models embedding RawJSON decode themselves like plain structs.
+	if reflect.PtrTo(t).Implements(unmarshalerType) && !reflect.PtrTo(t).Implements(rawModelType) {
+		w.walkUnmarshaler(path, key, data, t, actual)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+	case reflect.Bool:
+		w.expect(path, key, jsonBool, actual)
+	case reflect.String:
+		w.expect(path, key, jsonString, actual)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		w.expect(path, key, jsonNumber, actual)
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string.
+			w.expect(path, key, jsonString, actual)
+			return
+		}
+		if w.expect(path, key, jsonArray, actual) {
+			w.walkArray(path, key, data, t.Elem())
+		}
+	case reflect.Map:
+		if w.expect(path, key, jsonObject, actual) {
+			w.walkMap(path, key, data, t.Elem())
+		}
+	case reflect.Struct:
+		if w.expect(path, key, jsonObject, actual) {
+			w.walkStruct(path, key, data, t)
+		}
+	}
+}
+
+// walkUnmarshaler checks data against t, which decodes itself, reporting the
+// values it rejects. The elements of the arrays and objects decoded into
+// slice and map types, such as FlexSlice, are checked as well.
+func (w *driftWalker) walkUnmarshaler(path, key string, data []byte, t reflect.Type, actual string) {
+	if err := json.Unmarshal(data, reflect.New(t).Interface()); err != nil {
+		w.add(path, key, t.String(), actual)
+		return
+	}
+
+	switch {
+	case t.Kind() == reflect.Slice && actual == jsonArray:
+		w.walkArray(path, key, data, t.Elem())
+	case t.Kind() == reflect.Map && actual == jsonObject:
+		w.walkMap(path, key, data, t.Elem())
+	}
+}
+
+func (w *driftWalker) walkArray(path, key string, data []byte, elem reflect.Type) {
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return
+	}
+	for i, value := range values {
+		w.walk(path+"["+strconv.Itoa(i)+"]", key+"[*]", value, elem)
+	}
+}
+
+func (w *driftWalker) walkMap(path, key string, data []byte, elem reflect.Type) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return
+	}
+	for _, name := range sortedKeys(values) {
+		w.walk(path+"."+name, key+"."+name, values[name], elem)
+	}
+}
+
+func (w *driftWalker) walkStruct(path, key string, data []byte, t reflect.Type) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return
+	}
+	fields := structFields(t)
+	for _, name := range sortedKeys(values) {
+		f, ok := fields.lookup(name)
+		switch {
+		case !ok:
+			w.add(path+"."+name, key+"."+name, "", jsonType(values[name]))
+		case f.quoted:
+			// The ",string" option encodes scalars as strings.
+			w.expect(path+"."+name, key+"."+name, jsonString, jsonType(values[name]))
+		default:
+			w.walk(path+"."+name, key+"."+name, values[name], f.typ)
+		}
+	}
+}
+
+// expect reports actual if it is not the expected type, and returns whether
+// it is.
+func (w *driftWalker) expect(path, key, expected, actual string) bool {
+	if actual == expected {
+		return true
+	}
+	w.add(path, key, expected, actual)
+	return false
+}
+
+func (w *driftWalker) add(path, key, expected, actual string) {
+	if expected != "" {
+		w.mismatches[path] = true
+	}
+	k := key + " " + expected + " " + actual
+	if issue, ok := w.issues[k]; ok {
+		issue.Count++
+		return
+	}
+	w.issues[k] = &DriftIssue{Path: path, Expected: expected, Actual: actual, Count: 1}
+}
+
+// nullValues returns data, found at path, with the values at the given paths
+// replaced by null, and whether any was. The other values are kept as they
+// are, along with the order of the object fields.
+func nullValues(path string, data []byte, paths map[string]bool) ([]byte, bool) {
+	if paths[path] {
+		return []byte("null"), true
+	}
+
+	var (
+		buf     bytes.Buffer
+		changed bool
+	)
+	switch jsonType(data) {
+	case jsonArray:
+		var values []json.RawMessage
+		if err := json.Unmarshal(data, &values); err != nil {
+			return data, false
+		}
+		buf.WriteByte('[')
+		for i, value := range values {
+			value, ok := nullValues(path+"["+strconv.Itoa(i)+"]", value, paths)
+			changed = changed || ok
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(value)
+		}
+		buf.WriteByte(']')
+	case jsonObject:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if _, err := dec.Token(); err != nil {
+			return data, false
+		}
+		buf.WriteByte('{')
+		for i := 0; dec.More(); i++ {
+			tok, err := dec.Token()
+			if err != nil {
+				return data, false
+			}
+			name, _ := tok.(string)
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return data, false
+			}
+			value, ok := nullValues(path+"."+name, value, paths)
+			changed = changed || ok
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(name)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	if !changed {
+		return data, false
+	}
+	return buf.Bytes(), true
+}
+
+// jsonType returns the type of the JSON value data.
+func jsonType(data []byte) string {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return jsonInvalid
+	}
+	switch c := data[0]; {
+	case c == 'n':
+		return jsonNull
+	case c == 't' || c == 'f':
+		return jsonBool
+	case c == '"':
+		return jsonString
+	case c == '[':
+		return jsonArray
+	case c == '{':
+		return jsonObject
+	case c == '-' || ('0' <= c && c <= '9'):
+		return jsonNumber
+	}
+	return jsonInvalid
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// driftField is a struct field as seen by encoding/json.
+type driftField struct {
+	typ    reflect.Type
+	quoted bool
+}
+
+// driftFields are the fields of a struct type, keyed by JSON name.
+type driftFields map[string]driftField
+
+// lookup returns the field named name, matching case-insensitively as
+// encoding/json does when there is no exact match.
+func (fs driftFields) lookup(name string) (driftField, bool) {
+	if f, ok := fs[name]; ok {
+		return f, true
+	}
+	for n, f := range fs {
+		if strings.EqualFold(n, name) {
+			return f, true
+		}
+	}
+	return driftField{}, false
+}
+
+var driftFieldCache sync.Map // map[reflect.Type]driftFields
+
+// structFields returns the fields of the struct type t decoded by
+// encoding/json, including those promoted from embedded structs.
+func structFields(t reflect.Type) driftFields {
+	if fs, ok := driftFieldCache.Load(t); ok {
+		return fs.(driftFields)
+	}
+	fs := driftFields{}
+	addStructFields(fs, t)
+	driftFieldCache.Store(t, fs)
+	return fs
+}
+
+func addStructFields(fs driftFields, t reflect.Type) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if _, ok := fs[name]; ok {
+			continue
+		}
+		quoted := false
+		for _, opt := range strings.Split(opts, ",") {
+			quoted = quoted || opt == "string"
+		}
+		fs[name] = driftField{typ: sf.Type, quoted: quoted}
+	}
+
+	// Fields of the outer struct take precedence over promoted ones.
+	for _, et := range embedded {
+		addStructFields(fs, et)
+	}
+}
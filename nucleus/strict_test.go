@@ -0,0 +1,118 @@
+package nucleus_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+	"github.com/rsclarke/go-nucleus/nucleus/nucleustest"
+)
+
+const driftedAssets = `[
+	{"asset_id":"1","asset_name":"a","finding_count_high":"2"},
+	{"asset_id":2,"asset_name":"b","finding_count_high":[1],"asset_owner":"x"},
+	{"asset_id":"3","asset_name":"c","asset_owner":"y"}
+]`
+
+func TestStrictDecodingDrift(t *testing.T) {
+	srv := nucleustest.NewServer()
+	defer srv.Close()
+	projectID := srv.Store.AddProject(nucleus.Project{Name: "p"})
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []nucleus.DriftIssue
+		wantIDs []string
+	}{
+		{
+			name:    "no drift",
+			body:    `[{"asset_id":"1","asset_name":"a"}]`,
+			wantIDs: []string{"1"},
+		},
+		{
+			name: "drift",
+			body: driftedAssets,
+			want: []nucleus.DriftIssue{
+				{Path: "$[1].asset_id", Expected: "string", Actual: "number", Count: 1},
+				{Path: "$[1].asset_owner", Actual: "string", Count: 2},
+				{Path: "$[1].finding_count_high", Expected: "nucleus.FlexInt", Actual: "array", Count: 1},
+			},
+			wantIDs: []string{"1", "", "3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var drift *nucleus.SchemaDrift
+			client := srv.Client(nucleus.WithStrictDecoding(&nucleus.StrictDecoding{
+				OnDrift: func(d *nucleus.SchemaDrift) { drift = d },
+			}))
+			srv.InjectFault(nucleustest.Fault{Method: "GET", Path: "projects/*/assets", Times: 1, Body: tt.body})
+
+			assets, _, err := client.Projects.ListAssets(context.Background(), projectID, nucleus.ListAssetsRequest{})
+			if err != nil {
+				t.Fatalf("ListAssets error = %v", err)
+			}
+			var ids []string
+			for _, a := range assets {
+				ids = append(ids, a.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ListAssets IDs = %q, want %q", ids, tt.wantIDs)
+			}
+
+			if tt.want == nil {
+				if drift != nil {
+					t.Errorf("OnDrift called with %v", drift)
+				}
+				return
+			}
+			if drift == nil {
+				t.Fatal("OnDrift not called")
+			}
+			if drift.Operation != "Projects.ListAssets" {
+				t.Errorf("Operation = %q, want Projects.ListAssets", drift.Operation)
+			}
+			if !reflect.DeepEqual(drift.Issues, tt.want) {
+				t.Errorf("Issues = %+v, want %+v", drift.Issues, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrictDecodingTolerant(t *testing.T) {
+	srv := nucleustest.NewServer()
+	defer srv.Close()
+	projectID := srv.Store.AddProject(nucleus.Project{Name: "p"})
+	srv.InjectFault(nucleustest.Fault{Method: "GET", Path: "projects/*/assets", Body: driftedAssets})
+
+	client := srv.Client(nucleus.WithStrictDecoding(&nucleus.StrictDecoding{}))
+	assets, _, err := client.Projects.ListAssets(context.Background(), projectID, nucleus.ListAssetsRequest{})
+	if err != nil {
+		t.Fatalf("ListAssets error = %v", err)
+	}
+	if len(assets) != 3 {
+		t.Fatalf("ListAssets returned %d assets, want 3", len(assets))
+	}
+	if a := assets[1]; a.Name != "b" || a.ID != "" || a.FindingCountHigh.Value != 0 || string(a.Extra["asset_owner"]) != `"x"` {
+		t.Errorf("drifted asset = %+v", a)
+	}
+	if a := assets[0]; a.FindingCountHigh.Value != 2 {
+		t.Errorf("FindingCountHigh = %v, want 2", a.FindingCountHigh.Value)
+	}
+
+	client = srv.Client(nucleus.WithStrictDecoding(&nucleus.StrictDecoding{Fail: true}))
+	if _, _, err := client.Projects.ListAssets(context.Background(), projectID, nucleus.ListAssetsRequest{}); err == nil {
+		t.Error("ListAssets with Fail error = nil, want an error")
+	}
+
+	srv.ClearFaults()
+	srv.InjectFault(nucleustest.Fault{Method: "GET", Path: "projects/*/assets", Body: `[{"asset_id":"1","asset_owner":"x"}]`})
+	_, _, err = client.Projects.ListAssets(context.Background(), projectID, nucleus.ListAssetsRequest{})
+	var drift *nucleus.SchemaDrift
+	if !errors.As(err, &drift) || len(drift.Issues) != 1 {
+		t.Errorf("ListAssets with Fail error = %v, want a *SchemaDrift with one issue", err)
+	}
+}
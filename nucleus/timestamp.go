@@ -0,0 +1,183 @@
+package nucleus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultTimestampLayout is the layout in which the API sends most dates, and
+// in which a Timestamp not decoded from the API is encoded. Times in this
+// layout are in UTC.
+const DefaultTimestampLayout = "2006-01-02 15:04:05"
+
+// Pseudo-layouts of Timestamps decoded from Unix times.
+const (
+	layoutUnix      = "unix"
+	layoutUnixMilli = "unixmilli"
+)
+
+// unixMilliThreshold is the smallest Unix time taken to be in milliseconds
+// rather than seconds, a date in 33658 when read as seconds.
+const unixMilliThreshold = 1e12
+
+// timestampLayouts are the layouts of the dates sent by the API, tried in
+// order. Fractional seconds are accepted by all those with seconds.
+var timestampLayouts = []string{
+	DefaultTimestampLayout,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// zeroTimestamps are the values the API sends for dates that are not set.
+var zeroTimestamps = []string{
+	"",
+	"0000-00-00",
+	"0000-00-00 00:00:00",
+}
+
+// Timestamp is a date sent by the API, which uses several formats: date and
+// time strings with or without a time zone, dates alone, and Unix times in
+// seconds or milliseconds, as JSON numbers or strings. Dates that are not set,
+// sent as null, an empty string, zero or 0000-00-00, decode to a zero
+// Timestamp, which IsZero reports.
+//
+// A Timestamp keeps the JSON it was decoded from, which it is encoded as, so
+// that it is sent back to the API as received, including the fractional
+// seconds and the text of the dates that are not set. Once changed to another
+// time it is encoded in the format it was decoded from. Other Timestamps, such
+// as Timestamp{Time: t}, are encoded in DefaultTimestampLayout, and as an
+// empty string when zero.
+//
+// Compare Timestamps with the methods of time.Time, as in
+// a.Before(b.Time), rather than with ==.
+type Timestamp struct {
+	time.Time
+
+	// layout is that of the decoded value, a Unix pseudo-layout, or empty
+	// for DefaultTimestampLayout.
+	layout string
+
+	// quoted is set for Unix times sent as JSON strings rather than
+	// numbers.
+	quoted bool
+
+	// raw is the JSON the Timestamp was decoded from, and received the time
+	// it decoded to.
+	raw      string
+	received time.Time
+}
+
+// unixTimestamp returns the Timestamp of the Unix time n, in seconds or
+// milliseconds. Zero is the zero Timestamp.
+func unixTimestamp(n int64) Timestamp {
+	switch {
+	case n == 0:
+		return Timestamp{layout: layoutUnix}
+	case n >= unixMilliThreshold || n <= -unixMilliThreshold:
+		return Timestamp{Time: time.Unix(0, n*int64(time.Millisecond)).UTC(), layout: layoutUnixMilli}
+	}
+	return Timestamp{Time: time.Unix(n, 0).UTC(), layout: layoutUnix}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		*t = Timestamp{}
+		return nil
+	}
+
+	if len(data) == 0 || data[0] != '"' {
+		n, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return fmt.Errorf("nucleus: cannot decode %s as a timestamp", data)
+		}
+		*t = unixTimestamp(n)
+		t.raw, t.received = string(data), t.Time
+		return nil
+	}
+
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("nucleus: cannot decode %s as a timestamp: %v", data, err)
+	}
+	ts, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	ts.quoted = true
+	ts.raw, ts.received = string(data), ts.Time
+	*t = ts
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.raw != "" && t.Time.Equal(t.received) {
+		return []byte(t.raw), nil
+	}
+
+	switch t.layout {
+	case layoutUnix, layoutUnixMilli:
+		var n int64
+		if !t.IsZero() {
+			n = t.Unix()
+			if t.layout == layoutUnixMilli {
+				n = t.UnixNano() / int64(time.Millisecond)
+			}
+		}
+		s := strconv.FormatInt(n, 10)
+		if t.quoted {
+			s = strconv.Quote(s)
+		}
+		return []byte(s), nil
+	}
+
+	if t.IsZero() {
+		return []byte(`""`), nil
+	}
+
+	var s string
+	switch t.layout {
+	case "":
+		s = t.UTC().Format(DefaultTimestampLayout)
+	case time.RFC3339:
+		s = t.Time.Format(time.RFC3339Nano)
+	default:
+		s = t.UTC().Format(t.layout)
+	}
+	return []byte(strconv.Quote(s)), nil
+}
+
+// isEmpty reports whether t is zero and was not decoded from a date, as when
+// it is not set.
+func (t Timestamp) isEmpty() bool {
+	return t.IsZero() && (t.raw == "" || t.raw == `""`)
+}
+
+// ParseTimestamp parses s in any of the formats of the dates sent by the API.
+// Times without a time zone are taken to be in UTC.
+func ParseTimestamp(s string) (Timestamp, error) {
+	for _, z := range zeroTimestamps {
+		if s == z {
+			return Timestamp{}, nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return unixTimestamp(n), nil
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			if layout == DefaultTimestampLayout {
+				layout = ""
+			}
+			return Timestamp{Time: t, layout: layout}, nil
+		}
+	}
+	return Timestamp{}, fmt.Errorf("nucleus: cannot parse %q as a timestamp", s)
+}
@@ -0,0 +1,125 @@
+package nucleus_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{in: "2023-01-02 03:04:05", want: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "2023-01-02 03:04:05.5", want: time.Date(2023, 1, 2, 3, 4, 5, 5e8, time.UTC)},
+		{in: "2023-01-02T03:04:05Z", want: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "2023-01-02T03:04:05+02:00", want: time.Date(2023, 1, 2, 1, 4, 5, 0, time.UTC)},
+		{in: "2023-01-02T03:04:05.123", want: time.Date(2023, 1, 2, 3, 4, 5, 123e6, time.UTC)},
+		{in: "2023-01-02", want: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{in: "1672628645", want: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "1672628645123", want: time.Date(2023, 1, 2, 3, 4, 5, 123e6, time.UTC)},
+		{in: ""},
+		{in: "0000-00-00"},
+		{in: "0000-00-00 00:00:00"},
+		{in: "0"},
+	}
+	for _, tt := range tests {
+		got, err := nucleus.ParseTimestamp(tt.in)
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.in, got.Time, tt.want)
+		}
+	}
+
+	if _, err := nucleus.ParseTimestamp("yesterday"); err == nil {
+		t.Error("ParseTimestamp(yesterday) error = nil, want an error")
+	}
+}
+
+func TestTimestampRoundTrip(t *testing.T) {
+	tests := []string{
+		`"2023-01-02 03:04:05"`,
+		`"2023-01-02 03:04:05.5"`,
+		`"2023-01-02T03:04:05.123"`,
+		`"2023-01-02T03:04:05.1+02:00"`,
+		`"2023-01-02"`,
+		`1672628645`,
+		`"1672628645"`,
+		`1672628645123`,
+		`""`,
+		`"0000-00-00"`,
+		`"0000-00-00 00:00:00"`,
+		`0`,
+	}
+	for _, in := range tests {
+		var ts nucleus.Timestamp
+		if err := json.Unmarshal([]byte(in), &ts); err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", in, err)
+			continue
+		}
+		out, err := json.Marshal(ts)
+		if err != nil {
+			t.Errorf("Marshal(%s) error = %v", in, err)
+			continue
+		}
+		if string(out) != in {
+			t.Errorf("Marshal(Unmarshal(%s)) = %s", in, out)
+		}
+	}
+}
+
+func TestTimestampChanged(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: `"2023-01-02 03:04:05"`, want: `"2023-01-02 04:04:05"`},
+		{in: `"2023-01-02T03:04:05+02:00"`, want: `"2023-01-02T04:04:05+02:00"`},
+		{in: `1672628645`, want: `1672632245`},
+		{in: `"1672628645"`, want: `"1672632245"`},
+	}
+	for _, tt := range tests {
+		var ts nucleus.Timestamp
+		if err := json.Unmarshal([]byte(tt.in), &ts); err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		ts.Time = ts.Add(time.Hour)
+		out, _ := json.Marshal(ts)
+		if string(out) != tt.want {
+			t.Errorf("Marshal of %s plus an hour = %s, want %s", tt.in, out, tt.want)
+		}
+	}
+
+	out, _ := json.Marshal(nucleus.Timestamp{Time: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)})
+	if string(out) != `"2023-01-02 03:04:05"` {
+		t.Errorf("Marshal of a new Timestamp = %s", out)
+	}
+}
+
+func TestAssetInactiveDate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: `{"asset_name":"a","asset_inactive_date":"0000-00-00 00:00:00"}`, want: `{"asset_name":"a","asset_inactive_date":"0000-00-00 00:00:00"}`},
+		{in: `{"asset_name":"a","asset_inactive_date":"2023-01-02 03:04:05"}`, want: `{"asset_name":"a","asset_inactive_date":"2023-01-02 03:04:05"}`},
+		{in: `{"asset_name":"a","asset_inactive_date":""}`, want: `{"asset_name":"a"}`},
+		{in: `{"asset_name":"a"}`, want: `{"asset_name":"a"}`},
+	}
+	for _, tt := range tests {
+		var a nucleus.Asset
+		if err := json.Unmarshal([]byte(tt.in), &a); err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		out, _ := json.Marshal(a)
+		if string(out) != tt.want {
+			t.Errorf("Marshal(Unmarshal(%s)) = %s, want %s", tt.in, out, tt.want)
+		}
+	}
+}
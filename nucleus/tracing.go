@@ -0,0 +1,81 @@
+package nucleus
+
+import (
+	"context"
+	"net/http"
+)
+
+// Span attribute keys set by a Client.
+const (
+	AttrOperation  = "nucleus.operation"
+	AttrProjectID  = "nucleus.project_id"
+	AttrAssetID    = "nucleus.asset_id"
+	AttrAttempts   = "nucleus.attempts"
+	AttrHTTPMethod = "http.request.method"
+	AttrHTTPURL    = "url.full"
+	AttrHTTPStatus = "http.response.status_code"
+)
+
+// Tracer creates spans around the API calls made by a Client. It is a small
+// abstraction over tracing libraries such as OpenTelemetry so that the
+// nucleus package has no dependencies of its own.
+type Tracer interface {
+	// Start starts a span named name as a child of any span in ctx and
+	// returns a context containing the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+
+	// Inject adds the headers propagating the trace context in ctx to h.
+	Inject(ctx context.Context, h http.Header)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SetAttribute sets an attribute of the span. value is a string, int or
+	// bool.
+	SetAttribute(key string, value interface{})
+
+	// RecordError records err as the cause of the span's failure.
+	RecordError(err error)
+
+	// End completes the span.
+	End()
+}
+
+// trace wraps h to run each call in a span started by c.Tracer.
+func (c *Client) trace(h Handler) Handler {
+	if c.Tracer == nil {
+		return h
+	}
+	return func(ctx context.Context, call *Call) (*Response, error) {
+		name := call.Operation
+		if name == "" {
+			name = "nucleus." + call.Request.Method
+		}
+		ctx, span := c.Tracer.Start(ctx, name)
+		defer span.End()
+
+		if call.Operation != "" {
+			span.SetAttribute(AttrOperation, call.Operation)
+		}
+		if call.ProjectID != "" {
+			span.SetAttribute(AttrProjectID, call.ProjectID)
+		}
+		if call.AssetID != "" {
+			span.SetAttribute(AttrAssetID, call.AssetID)
+		}
+		span.SetAttribute(AttrHTTPMethod, call.Request.Method)
+		span.SetAttribute(AttrHTTPURL, call.Request.URL.String())
+
+		c.Tracer.Inject(ctx, call.Request.Header)
+
+		resp, err := h(ctx, call)
+		span.SetAttribute(AttrAttempts, call.Attempts)
+		if resp != nil {
+			span.SetAttribute(AttrHTTPStatus, resp.StatusCode)
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		return resp, err
+	}
+}
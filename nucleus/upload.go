@@ -0,0 +1,231 @@
+package nucleus
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultUploadField is the form field of an UploadFile without FieldName.
+const defaultUploadField = "file"
+
+// Upload is the multipart/form-data body of a request created with
+// NewUploadRequest.
+type Upload struct {
+	// Fields are the form fields sent before the files, in the order of
+	// their names.
+	Fields map[string]string
+
+	// Files are the files sent, in order.
+	Files []UploadFile
+
+	// Progress, if set, is called as the body is sent with the number of
+	// bytes written so far and the total size of the body, or -1 if the
+	// size of a file is unknown. It is called from the goroutine writing the
+	// body.
+	Progress func(written, total int64)
+}
+
+// UploadFile is a file sent in an Upload.
+type UploadFile struct {
+	// FieldName is the form field of the file, "file" if empty.
+	FieldName string
+
+	// FileName is the name of the file sent to the API.
+	FileName string
+
+	// ContentType is the type of the file, application/octet-stream if
+	// empty.
+	ContentType string
+
+	// Content is read until EOF as the request is sent. It is closed
+	// afterwards if it is an io.Closer.
+	Content io.Reader
+
+	// Size is the number of bytes in Content, or -1 if unknown. When the
+	// size of every file is known the request is sent with a Content-Length
+	// rather than chunked. A Size of 0 is taken as unknown unless Content
+	// is nil.
+	Size int64
+}
+
+// NewUploadRequest creates an API request sending upload as
+// multipart/form-data, for endpoints importing scans or attachments. The
+// files are streamed as the request is sent rather than held in memory,
+// which means that the request cannot be retried. It is sent with Client.Do
+// like any other request.
+func (c *Client) NewUploadRequest(method, urlStr string, upload *Upload) (*http.Request, error) {
+	if upload == nil {
+		return nil, errors.New("upload must not be nil")
+	}
+	for i, f := range upload.Files {
+		if f.Content == nil && f.Size != 0 {
+			return nil, fmt.Errorf("upload file %d (%v) has no content", i, f.FileName)
+		}
+	}
+
+	req, err := c.NewRequest(method, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &uploadBody{upload: upload, boundary: multipart.NewWriter(nil).Boundary()}
+	total, err := body.size()
+	if err != nil {
+		return nil, err
+	}
+	body.total = total
+
+	req.Body = body
+	req.ContentLength = total
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+body.boundary)
+	return req, nil
+}
+
+// uploadBody is the body of an upload request. The goroutine writing the
+// multipart form into a pipe is started by the first Read, so that a request
+// that is never sent does not leak it.
+type uploadBody struct {
+	upload   *Upload
+	boundary string
+	total    int64
+
+	once sync.Once
+	pr   *io.PipeReader
+}
+
+func (b *uploadBody) Read(p []byte) (int, error) {
+	b.start()
+	return b.pr.Read(p)
+}
+
+func (b *uploadBody) Close() error {
+	b.start()
+	return b.pr.Close()
+}
+
+func (b *uploadBody) start() {
+	b.once.Do(func() {
+		pr, pw := io.Pipe()
+		b.pr = pr
+		go func() {
+			w := &progressWriter{w: pw, total: b.total, progress: b.upload.Progress}
+			pw.CloseWithError(b.write(w, true))
+		}()
+	})
+}
+
+// write writes the multipart form to w. The file contents are only written,
+// and closed, if withContent is set.
+func (b *uploadBody) write(w io.Writer, withContent bool) error {
+	if withContent {
+		defer b.closeFiles()
+	}
+
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(b.boundary); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(b.upload.Fields))
+	for name := range b.upload.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := mw.WriteField(name, b.upload.Fields[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range b.upload.Files {
+		part, err := mw.CreatePart(fileHeader(f))
+		if err != nil {
+			return err
+		}
+		if withContent && f.Content != nil {
+			if _, err := io.Copy(part, f.Content); err != nil {
+				return fmt.Errorf("upload file %v: %w", f.FileName, err)
+			}
+		}
+	}
+	return mw.Close()
+}
+
+// size returns the length of the body, or -1 if the size of a file is
+// unknown.
+func (b *uploadBody) size() (int64, error) {
+	var n int64
+	for _, f := range b.upload.Files {
+		if f.Size < 0 || (f.Size == 0 && f.Content != nil) {
+			return -1, nil
+		}
+		n += f.Size
+	}
+	cw := &countingWriter{}
+	if err := b.write(cw, false); err != nil {
+		return 0, err
+	}
+	return n + cw.n, nil
+}
+
+func (b *uploadBody) closeFiles() {
+	for _, f := range b.upload.Files {
+		if c, ok := f.Content.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// fileHeader returns the MIME header of the part holding f.
+func fileHeader(f UploadFile) textproto.MIMEHeader {
+	field := f.FieldName
+	if field == "" {
+		field = defaultUploadField
+	}
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field), quoteEscaper.Replace(f.FileName)))
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+// progressWriter reports the bytes written to w to progress.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	if w.progress != nil && n > 0 {
+		w.progress(w.written, w.total)
+	}
+	return n, err
+}
+
+// countingWriter counts and discards the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
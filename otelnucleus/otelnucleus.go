@@ -0,0 +1,113 @@
+// Package otelnucleus adapts OpenTelemetry to the nucleus.Tracer interface.
+// It lives in its own module so that the nucleus package stays free of
+// dependencies.
+//
+//	client, err := nucleus.NewClientWithOptions(org,
+//		nucleus.WithTracer(otelnucleus.NewTracer()))
+package otelnucleus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rsclarke/go-nucleus/nucleus"
+)
+
+const instrumentationName = "github.com/rsclarke/go-nucleus/otelnucleus"
+
+// Option configures a Tracer.
+type Option func(*Tracer)
+
+// WithTracerProvider sets the TracerProvider used to create spans instead of
+// the global one.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.provider = tp
+	}
+}
+
+// WithPropagator sets the propagator used to inject the trace context into
+// requests instead of the global one.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(t *Tracer) {
+		t.propagator = p
+	}
+}
+
+// Tracer implements nucleus.Tracer with OpenTelemetry.
+type Tracer struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+}
+
+var _ nucleus.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a Tracer using the global TracerProvider and propagator
+// unless configured otherwise by opts.
+func NewTracer(opts ...Option) *Tracer {
+	t := &Tracer{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.provider == nil {
+		t.provider = otel.GetTracerProvider()
+	}
+	if t.propagator == nil {
+		t.propagator = otel.GetTextMapPropagator()
+	}
+	t.tracer = t.provider.Tracer(instrumentationName)
+	return t
+}
+
+// Start implements nucleus.Tracer.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, nucleus.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, &Span{span: span}
+}
+
+// Inject implements nucleus.Tracer.
+func (t *Tracer) Inject(ctx context.Context, h http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// Span implements nucleus.Span with an OpenTelemetry span.
+type Span struct {
+	span trace.Span
+}
+
+// SetAttribute implements nucleus.Span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	var kv attribute.KeyValue
+	switch v := value.(type) {
+	case string:
+		kv = attribute.String(key, v)
+	case int:
+		kv = attribute.Int(key, v)
+	case int64:
+		kv = attribute.Int64(key, v)
+	case bool:
+		kv = attribute.Bool(key, v)
+	default:
+		kv = attribute.String(key, fmt.Sprint(v))
+	}
+	s.span.SetAttributes(kv)
+}
+
+// RecordError implements nucleus.Span.
+func (s *Span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End implements nucleus.Span.
+func (s *Span) End() {
+	s.span.End()
+}